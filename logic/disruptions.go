@@ -0,0 +1,53 @@
+package logic
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/rahulbharuka/train-route-finder/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddDisruption closes a station, a train line or a segment between two adjacent stations.
+func (h *handlerImpl) AddDisruption(ctx *gin.Context) {
+	req := &repository.Disruption{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Println("invalid disruption request")
+		handlerError(ctx, http.StatusBadRequest, errors.New("invalid disruption request"))
+		return
+	}
+
+	disruption, err := h.repo.AddDisruption(req)
+	if err == repository.ErrInvalidRequest {
+		handlerError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		handlerError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, disruption)
+}
+
+// RemoveDisruption clears an active disruption.
+func (h *handlerImpl) RemoveDisruption(ctx *gin.Context) {
+	err := h.repo.RemoveDisruption(ctx.Param("id"))
+	if err == repository.ErrDisruptionNotFound {
+		handlerError(ctx, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		handlerError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListDisruptions lists active disruptions.
+func (h *handlerImpl) ListDisruptions(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.repo.ListDisruptions())
+}
@@ -2,6 +2,9 @@ package logic
 
 import (
 	"errors"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/rahulbharuka/train-route-finder/repository"
 
@@ -13,6 +16,12 @@ var errRouteNotFound = errors.New("no route exist")
 // Handler is the logic handler interface
 type Handler interface {
 	Routes(ctx *gin.Context)
+	Reachable(ctx *gin.Context)
+	Matrix(ctx *gin.Context)
+	SearchStations(ctx *gin.Context)
+	AddDisruption(ctx *gin.Context)
+	RemoveDisruption(ctx *gin.Context)
+	ListDisruptions(ctx *gin.Context)
 }
 
 // handlerImpl is a implementation of Handler interface
@@ -33,3 +42,47 @@ func handlerError(ctx *gin.Context, errCode int, err error) {
 		"message": err.Error(),
 	})
 }
+
+// languages is a helper function to return preferred languages of the request, most preferred first.
+// The lang query parameter comes first, followed by Accept-Language header languages in decreasing order of quality.
+func languages(ctx *gin.Context) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var accepted []language
+	for _, v := range strings.Split(ctx.GetHeader("Accept-Language"), ",") {
+		parts := strings.Split(strings.TrimSpace(v), ";")
+		l := language{tag: strings.TrimSpace(parts[0]), quality: 1}
+		for _, param := range parts[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				l.quality, _ = strconv.ParseFloat(q[2:], 64)
+			}
+		}
+		if l.tag != "" && l.tag != "*" && l.quality > 0 {
+			accepted = append(accepted, l)
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+
+	var list []string
+	if lang := ctx.Query("lang"); lang != "" {
+		list = append(list, lang)
+	}
+	for _, l := range accepted {
+		list = append(list, l.tag)
+	}
+	return list
+}
+
+// queryList is a helper function to return list query parameter passed either repeated or comma separated.
+func queryList(ctx *gin.Context, key string) []string {
+	var list []string
+	for _, v := range ctx.QueryArray(key) {
+		list = append(list, strings.Split(v, ",")...)
+	}
+	return list
+}
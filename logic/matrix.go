@@ -0,0 +1,70 @@
+package logic
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/repository"
+	"github.com/rahulbharuka/train-route-finder/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// matrixRequest is the travel matrix request body.
+type matrixRequest struct {
+	Sources      []string `json:"sources"`
+	Destinations []string `json:"destinations"`
+	JourneyTime  string   `json:"journeyTime"`
+	Mode         string   `json:"mode"`
+}
+
+// Matrix finds best route from every source to every destination.
+func (h *handlerImpl) Matrix(ctx *gin.Context) {
+	req := &matrixRequest{}
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		log.Println("invalid matrix request")
+		handlerError(ctx, http.StatusBadRequest, errors.New("invalid matrix request"))
+		return
+	}
+
+	var err error
+	var journeyTime time.Time
+	mode := types.RMStops
+	if req.JourneyTime != "" {
+		journeyTime, err = time.Parse("2006-01-02T15:04", req.JourneyTime)
+		if err != nil {
+			log.Println("invalid journey start time")
+			handlerError(ctx, http.StatusBadRequest, errors.New("invalid journey start time"))
+			return
+		}
+		mode = types.RMTime
+	}
+
+	if req.Mode != "" {
+		mode = types.ConvertToRouteMode(req.Mode)
+		if mode == types.RMInvalid || mode == types.RMPareto || (mode == types.RMTime && journeyTime.IsZero()) {
+			log.Println("invalid route mode")
+			handlerError(ctx, http.StatusBadRequest, errors.New("invalid route mode"))
+			return
+		}
+	}
+
+	resp, err := h.repo.FindMatrix(&repository.MatrixQuery{
+		Sources:      req.Sources,
+		Destinations: req.Destinations,
+		JourneyTime:  journeyTime,
+		Mode:         mode,
+	})
+	if err == repository.ErrInvalidRequest {
+		handlerError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		handlerError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,50 @@
+package logic
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reachable finds every station reachable from source within the travel time budget.
+func (h *handlerImpl) Reachable(ctx *gin.Context) {
+	journeyTime, err := time.Parse("2006-01-02T15:04", ctx.Query("journeyTime"))
+	if err != nil {
+		log.Println("invalid journey start time")
+		handlerError(ctx, http.StatusBadRequest, errors.New("invalid journey start time"))
+		return
+	}
+
+	maxMinutes, err := strconv.Atoi(ctx.Query("maxMinutes"))
+	if err != nil || maxMinutes <= 0 {
+		log.Println("invalid max minutes")
+		handlerError(ctx, http.StatusBadRequest, errors.New("invalid max minutes"))
+		return
+	}
+
+	resp, err := h.repo.FindReachable(&repository.ReachableQuery{
+		Source:      ctx.Query("src"),
+		JourneyTime: journeyTime,
+		MaxMinutes:  maxMinutes,
+	})
+	if err == repository.ErrInvalidRequest {
+		handlerError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := err.(*repository.RouteNotFoundError); ok {
+		handlerError(ctx, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		handlerError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/rahulbharuka/train-route-finder/repository"
+	"github.com/rahulbharuka/train-route-finder/types"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,7 +26,7 @@ func (h *handlerImpl) Routes(ctx *gin.Context) {
 
 	var err error
 	var journeyTime time.Time
-	var computeTimeCost bool
+	mode := types.RMStops
 	jTime := ctx.Query("journeyTime")
 	if jTime != "" {
 		journeyTime, err = time.Parse("2006-01-02T15:04", jTime)
@@ -33,18 +35,85 @@ func (h *handlerImpl) Routes(ctx *gin.Context) {
 			handlerError(ctx, http.StatusBadRequest, errors.New("invalid journey start time"))
 			return
 		}
-		computeTimeCost = true
+		mode = types.RMTime
 	}
 
-	resp, err := h.repo.FindRoutes(source, destination, journeyTime, computeTimeCost)
+	var arriveBy time.Time
+	if aTime := ctx.Query("arriveBy"); aTime != "" {
+		if !journeyTime.IsZero() {
+			log.Println("journey start time and arrival time cannot be set together")
+			handlerError(ctx, http.StatusBadRequest, errors.New("journey start time and arrival time cannot be set together"))
+			return
+		}
+		arriveBy, err = time.Parse("2006-01-02T15:04", aTime)
+		if err != nil {
+			log.Println("invalid journey arrival time")
+			handlerError(ctx, http.StatusBadRequest, errors.New("invalid journey arrival time"))
+			return
+		}
+		mode = types.RMTime
+	}
+
+	if m := ctx.Query("mode"); m != "" {
+		mode = types.ConvertToRouteMode(m)
+		if mode == types.RMInvalid || ((mode == types.RMTime || mode == types.RMPareto) && journeyTime.IsZero() && arriveBy.IsZero()) {
+			log.Println("invalid route mode")
+			handlerError(ctx, http.StatusBadRequest, errors.New("invalid route mode"))
+			return
+		}
+	}
+
+	// number of routes to return is left unset unless requested, so repository applies its default.
+	// It's capped by the server configured max routes.
+	k := 0
+	if kStr := ctx.Query("k"); kStr != "" {
+		k, err = strconv.Atoi(kStr)
+		if err != nil || k <= 0 {
+			log.Println("invalid number of routes")
+			handlerError(ctx, http.StatusBadRequest, errors.New("invalid number of routes"))
+			return
+		}
+		if maxK := h.repo.MaxRoutes(); k > maxK {
+			k = maxK
+		}
+	}
+
+	via := queryList(ctx, "via")
+	resp, fewer, err := h.repo.FindRoutes(&repository.RouteQuery{
+		Source:        source,
+		Destination:   destination,
+		Via:           via,
+		AvoidStations: queryList(ctx, "avoidStations"),
+		AvoidLines:    queryList(ctx, "avoidLines"),
+		JourneyTime:   journeyTime,
+		ArriveBy:      arriveBy,
+		Mode:          mode,
+		K:             k,
+		Languages:     languages(ctx),
+	})
 	if err == repository.ErrInvalidRequest {
 		handlerError(ctx, http.StatusBadRequest, err)
 		return
 	}
-	if err == repository.ErrRouteNotFound {
+	if e, ok := err.(*repository.StationNotFoundError); ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message":     e.Error(),
+			"suggestions": e.Suggestions,
+		})
+		return
+	}
+	if _, ok := err.(*repository.RouteNotFoundError); ok || err == repository.ErrRouteNotFound {
 		handlerError(ctx, http.StatusNotFound, err)
 		return
 	}
+	if err != nil {
+		handlerError(ctx, http.StatusInternalServerError, err)
+		return
+	}
 
+	// flag that fewer than k routes exist, as found by the repository.
+	if fewer {
+		ctx.Header("X-Fewer-Routes", "true")
+	}
 	ctx.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,61 @@
+package logic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rahulbharuka/train-route-finder/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// repoStub is a repository handler which records route queries. Fewer routes exist than any k above 1.
+type repoStub struct {
+	repository.Handler
+	query *repository.RouteQuery
+}
+
+func (r *repoStub) FindRoutes(query *repository.RouteQuery) ([]*repository.Route, bool, error) {
+	r.query = query
+	return []*repository.Route{}, query.K > 1, nil
+}
+
+func (r *repoStub) MaxRoutes() int {
+	return 3
+}
+
+func TestRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	routes := func(url string) (*httptest.ResponseRecorder, *repoStub) {
+		repo := &repoStub{}
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodGet, url, nil)
+		(&handlerImpl{repo: repo}).Routes(ctx)
+		return w, repo
+	}
+
+	t.Run("default-route-count", func(t *testing.T) {
+		w, repo := routes("/routes?src=Jurong%20East&dst=Holland%20Village&journeyTime=2022-01-31T08:00")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 0, repo.query.K)
+		assert.Empty(t, w.Header().Get("X-Fewer-Routes"))
+	})
+
+	t.Run("requested-route-count", func(t *testing.T) {
+		w, repo := routes("/routes?src=Jurong%20East&dst=Holland%20Village&k=2")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, repo.query.K)
+		assert.Equal(t, "true", w.Header().Get("X-Fewer-Routes"))
+
+		w, repo = routes("/routes?src=Jurong%20East&dst=Holland%20Village&k=5")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 3, repo.query.K)
+
+		w, _ = routes("/routes?src=Jurong%20East&dst=Holland%20Village&k=0")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,24 @@
+package logic
+
+import (
+	"net/http"
+
+	"github.com/rahulbharuka/train-route-finder/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchStations finds stations matching the search query, best matches first.
+func (h *handlerImpl) SearchStations(ctx *gin.Context) {
+	resp, err := h.repo.SearchStations(ctx.Query("q"))
+	if err == repository.ErrInvalidRequest {
+		handlerError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		handlerError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
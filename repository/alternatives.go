@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/types"
+)
+
+// candidateFactor is the number of candidate routes searched per route to return, when alternatives filter is enabled.
+const candidateFactor = 3
+
+// unsetPercentage is the value of an alternatives filter percentage which is not configured. It disables its check.
+const unsetPercentage = -1
+
+// alternativesFilter is the configuration of reasonable alternatives. An unset percentage or a false flag
+// disables its check, while a zero percentage allows no extra cost or shared edges at all.
+type alternativesFilter struct {
+	maxExtraCost   int  // max percentage a route may cost more than the best route
+	maxSharedEdges int  // max percentage of its edges a route may share with a better route
+	noLineReuse    bool // whether a route may not take a train line again after leaving it
+}
+
+// newAlternativesFilter returns alternatives filter with every check disabled.
+func newAlternativesFilter() alternativesFilter {
+	return alternativesFilter{
+		maxExtraCost:   unsetPercentage,
+		maxSharedEdges: unsetPercentage,
+	}
+}
+
+// isEnabled checks whether any check of alternatives filter is enabled.
+func (f alternativesFilter) isEnabled() bool {
+	return f.maxExtraCost != unsetPercentage || f.maxSharedEdges != unsetPercentage || f.noLineReuse
+}
+
+// findAlternatives finds top-k routes from src to dst using Yen's algorithm, leaving out unreasonable alternatives
+// per configured alternatives filter. It searches more candidates than k, so that filtered ones are replaced.
+// It also returns whether fewer than k routes were found.
+func (h *handlerImpl) findAlternatives(adjMatrix adjacencyMatrix, src, dst, k int, journeyTime time.Time, backward bool, mode types.RouteMode) ([]int, [][]vertexState, bool, error) {
+	if !alternatives.isEnabled() {
+		return h.yen(adjMatrix, src, dst, k, journeyTime, backward, mode)
+	}
+
+	dist, paths, _, err := h.yen(adjMatrix, src, dst, k*candidateFactor, journeyTime, backward, mode)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	// candidates are in increasing order of cost, so the first one is the best route.
+	keptDist, keptPaths := []int{}, [][]vertexState{}
+	for i := 0; i < len(paths) && len(keptPaths) < k; i++ {
+		if i > 0 && !h.isReasonable(getRouteCost(dist[i], mode), paths[i], getRouteCost(dist[0], mode), keptPaths) {
+			continue
+		}
+		keptDist = append(keptDist, dist[i])
+		keptPaths = append(keptPaths, paths[i])
+	}
+	return keptDist, keptPaths, len(keptPaths) < k, nil
+}
+
+// isReasonable checks whether route of given cost is a reasonable alternative to the best route and given better
+// routes. Route cost is the tie-breaker in transfers mode, so that an extra interchange is not too costly by itself.
+func (h *handlerImpl) isReasonable(cost int, path []vertexState, bestCost int, betterPaths [][]vertexState) bool {
+	if alternatives.maxExtraCost != unsetPercentage && (cost-bestCost)*100 > bestCost*alternatives.maxExtraCost {
+		return false // route is too costly.
+	}
+
+	if alternatives.maxSharedEdges != unsetPercentage {
+		for _, betterPath := range betterPaths {
+			if getSharedEdges(path, betterPath)*100 > (len(path)-1)*alternatives.maxSharedEdges {
+				return false // route is a near duplicate of a better route.
+			}
+		}
+	}
+
+	if alternatives.noLineReuse {
+		left := map[string]bool{}
+		pathLines := getPathLines(path)
+		for i := 1; i < len(pathLines); i++ {
+			if pathLines[i] != pathLines[i-1] {
+				left[pathLines[i-1]] = true
+			}
+			if pathLines[i] != walkLine && left[pathLines[i]] {
+				return false // route takes a train line again after leaving it.
+			}
+		}
+	}
+	return true
+}
+
+// getSharedEdges returns number of edges of path which other path also travels in the same direction.
+func getSharedEdges(path, other []vertexState) int {
+	otherEdges := map[[2]int]bool{}
+	for i := 0; i+1 < len(other); i++ {
+		otherEdges[[2]int{other[i].stationIdx, other[i+1].stationIdx}] = true
+	}
+
+	shared := 0
+	for i := 0; i+1 < len(path); i++ {
+		if otherEdges[[2]int{path[i].stationIdx, path[i+1].stationIdx}] {
+			shared++
+		}
+	}
+	return shared
+}
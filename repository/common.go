@@ -1,7 +1,9 @@
 package repository
 
 import (
-	"fmt"
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/types"
 )
 
 // preparePath preapares path to passed destination.
@@ -13,42 +15,164 @@ func (h *handlerImpl) preparePath(dst int, prev []int, route *[]int) error {
 	return nil
 }
 
-// prepareResponse prepares and returns detailed route(s) in string format.
-func (h *handlerImpl) prepareRouteSteps(route []int) string {
-	var resp string
-	prevStationName := stationIndexMap[route[0]].name
-	newStationName := stationIndexMap[route[0]].name
-	prevTrainLine := ""
+// prepareRoute prepares route of given path with its cost, structured legs and steps in given language.
+// Caller sets the heading.
+func (h *handlerImpl) prepareRoute(adjMatrix adjacencyMatrix, path []vertexState, startTime time.Time, cost int, lang string) *Route {
+	legs := h.prepareRouteLegs(adjMatrix, path, startTime)
+	route := &Route{
+		Departure:   formatTime(startTime),
+		Source:      stationIndexMap[path[0].stationIdx].name,
+		Destination: stationIndexMap[path[len(path)-1].stationIdx].name,
+		Steps:       h.prepareRouteSteps(legs, startTime, lang),
+		Cost:        cost,
+		Legs:        legs,
+	}
+	trainLine := "" // last train line taken before the leg.
+	for _, leg := range legs {
+		route.Stops = route.Stops + leg.Stops
+		if isInterchange(trainLine, leg.Line) {
+			route.Interchanges++
+		}
+		if leg.Line != walkLine {
+			trainLine = leg.Line
+		}
+	}
+	return route
+}
+
+// getTerminus returns name of the last line-station opened by given date on train line, travelling from one
+// line-station to another.
+func getTerminus(lineCode, fromCode, toCode string, asOf time.Time) string {
+	stationCodes := trainLineMap[lineCode]
+	step := 1
+	if lineStationMap[toCode].lineStationIdx < lineStationMap[fromCode].lineStationIdx {
+		step = -1
+	}
+
+	terminus := toCode
+	for i := lineStationMap[toCode].lineStationIdx; i >= 0 && i < len(stationCodes); i = i + step {
+		if lineStationMap[stationCodes[i]].isOpen(asOf) {
+			terminus = stationCodes[i]
+		}
+	}
+	return lineStationMap[terminus].name
+}
+
+// getRouteCost returns route cost of given distance in route mode. In transfers mode, it's the tie-breaker of
+// number of interchanges i.e. number of stops or travel time.
+func getRouteCost(dist int, mode types.RouteMode) int {
+	if mode == types.RMTransfers {
+		return dist % interchangePenalty
+	}
+	return dist
+}
+
+// prepareRouteLegs splits route into legs on a single train line, or walking between stations.
+// If start time is set, every leg has its travel time, waiting time and interchange cost at the clock time
+// the rider reaches it, along with its departure and arrival time. Walking time is always set. If the route starts on a train line e.g. a via segment,
+// its first leg is charged as changing from that line.
+func (h *handlerImpl) prepareRouteLegs(adjMatrix adjacencyMatrix, route []vertexState, startTime time.Time) []*RouteLeg {
+	legs := []*RouteLeg{}
+	legStart := 0 // index of the station where current train line (or walk) starts.
+	elapsed := 0
+	var leg *RouteLeg
 	for i := 0; i+1 < len(route); i++ {
-		newTrainLine := h.getTrainLine(route[i], route[i+1])
+		from, to := route[i], route[i+1]
+		prevTrainLine, newTrainLine := from.line, to.line
+		ht := getHourTypeAt(startTime, elapsed, false)
 
-		if prevTrainLine != "" && newTrainLine != prevTrainLine {
-			resp = resp + fmt.Sprintf("Take %v line from %v to %v. ", prevTrainLine, prevStationName, newStationName)
-			resp = resp + fmt.Sprintf("Change from %v line to %v line. ", prevTrainLine, newTrainLine)
-			prevStationName = newStationName
+		if i == 0 || newTrainLine != prevTrainLine {
+			leg = &RouteLeg{Line: newTrainLine}
+			if !startTime.IsZero() && (prevTrainLine == "" || isInterchange(prevTrainLine, newTrainLine)) {
+				leg.WaitTime = getWaitTime(newTrainLine, ht)
+			}
+			if !startTime.IsZero() && isInterchange(prevTrainLine, newTrainLine) && prevTrainLine != walkLine {
+				leg.InterchangeCost = getInterchangeCost(from.stationIdx, prevTrainLine, newTrainLine, ht)
+			}
+			if !startTime.IsZero() {
+				leg.Departure = formatTime(addMinutes(startTime, elapsed+leg.InterchangeCost+leg.WaitTime, false))
+			}
+			legs = append(legs, leg)
+			legStart = i
+		}
+
+		switch {
+		case newTrainLine == walkLine:
+			leg.Duration = leg.Duration + walkingLinkMap[[2]int{from.stationIdx, to.stationIdx}]
+		case !startTime.IsZero():
+			leg.Duration = leg.Duration + h.getEdgeWeight(adjMatrix, from.stationIdx, to.stationIdx, newTrainLine, ht)
 		}
-		prevTrainLine = newTrainLine
-		newStationName = stationIndexMap[route[i+1]].name
+		h.prepareLegStations(leg, route[legStart:i+2], startTime)
+		elapsed = elapsed + h.getTravelCost(adjMatrix, from, to.stationIdx, newTrainLine, ht, false, types.RMTime)
+		if !startTime.IsZero() {
+			leg.Arrival = formatTime(addMinutes(startTime, elapsed, false))
+		}
+	}
+	return legs
+}
+
+// formatTime returns given clock time in route response time format. Zero time is empty.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
-	resp = resp + fmt.Sprintf("Take %v line from %v to %v.", prevTrainLine, prevStationName, newStationName)
-	return resp
+	return t.Format(timeFormat)
 }
 
-// getTrainLine returns the train line code between two stations.
-func (h *handlerImpl) getTrainLine(i, j int) string {
-	if len(stationIndexMap[i].codes) == 1 {
-		return stationIndexMap[i].codes[0][:2]
+// prepareLegStations sets board, alight and intermediate stations of leg travelling given stations,
+// and the terminus of its train line in the direction of travel as on given date.
+func (h *handlerImpl) prepareLegStations(leg *RouteLeg, stations []vertexState, asOf time.Time) {
+	board, alight := stationIndexMap[stations[0].stationIdx], stationIndexMap[stations[len(stations)-1].stationIdx]
+	leg.BoardStation, leg.BoardCode = board.name, board.getCode(leg.Line)
+	leg.AlightStation, leg.AlightCode = alight.name, alight.getCode(leg.Line)
+	if leg.Line != walkLine {
+		leg.Terminus = getTerminus(leg.Line, leg.BoardCode, leg.AlightCode, asOf)
 	}
-	if len(stationIndexMap[j].codes) == 1 {
-		return stationIndexMap[j].codes[0][:2]
+	leg.IntermediateStations = []string{}
+	for _, state := range stations[1 : len(stations)-1] {
+		leg.IntermediateStations = append(leg.IntermediateStations, stationIndexMap[state.stationIdx].name)
 	}
+	leg.Stops = len(stations) - 1
+}
 
-	for _, iCode := range stationIndexMap[i].codes {
-		for _, jCode := range stationIndexMap[j].codes {
-			if iCode[:2] == jCode[:2] {
-				return iCode[:2]
+// prepareRouteSteps renders route legs as detailed route in string format in given language.
+// If start time is set, every change of train line shows its transfer time at the clock time the rider reaches it.
+// Walking between stations is shown with its walking time instead of a change of train line.
+func (h *handlerImpl) prepareRouteSteps(legs []*RouteLeg, startTime time.Time, lang string) string {
+	steps := []string{}
+	for i, leg := range legs {
+		if i > 0 && legs[i-1].Line != walkLine && leg.Line != walkLine {
+			if startTime.IsZero() {
+				steps = append(steps, message(lang, msgChangeLine, legs[i-1].Line, leg.Line))
+			} else {
+				steps = append(steps, message(lang, msgChangeLineTime, legs[i-1].Line, leg.Line, leg.InterchangeCost))
 			}
 		}
+		board, alight := localizeStation(lang, leg.BoardStation), localizeStation(lang, leg.AlightStation)
+		if leg.Line == walkLine {
+			steps = append(steps, message(lang, msgWalk, board, alight, leg.Duration))
+		} else {
+			steps = append(steps, message(lang, msgTakeLine, leg.Line, localizeStation(lang, leg.Terminus), board, alight))
+		}
+	}
+	return joinSteps(lang, steps)
+}
+
+// getPathLines returns the train line taken between every two consecutive stations of the path.
+func getPathLines(path []vertexState) []string {
+	pathLines := []string{}
+	for i := 1; i < len(path); i++ {
+		pathLines = append(pathLines, path[i].line)
+	}
+	return pathLines
+}
+
+// reversePath returns a new path visiting states of given backward search path in reverse order. The train line
+// taken between two stations moves along with the hop, so every state keeps the line used to reach it.
+func reversePath(path []vertexState) []vertexState {
+	reversed := []vertexState{{stationIdx: path[len(path)-1].stationIdx}}
+	for i := len(path) - 2; i >= 0; i-- {
+		reversed = append(reversed, reversed[len(reversed)-1].next(path[i].stationIdx, path[i+1].line))
 	}
-	return ""
+	return reversed
 }
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"math"
+	"sort"
+)
+
+// secondsPerDay is the offset of previous service day's connection times from the journey day.
+const secondsPerDay = 24 * 60 * 60
+
+// timetableLeg is a part of a timetable journey on a single trip.
+type timetableLeg struct {
+	line      string
+	fromStop  string
+	toStop    string
+	departure int      // seconds since service day midnight.
+	arrival   int      // seconds since service day midnight.
+	stops     []string // ordered stop ids passed between from and to stop.
+	terminus  string   // stop id of the last stop of the trip.
+}
+
+// journeyPointer stores how a stop was reached in connection scan.
+// Either by a trip boarded at enter connection and left after exit connection, or by a transfer from another stop.
+type journeyPointer struct {
+	enter        *connection
+	exit         *connection
+	offset       int // seconds trip's service day times are ahead of the journey day's.
+	transferFrom string
+}
+
+// tripRun is a trip running on the journey day (offset 0) or on the previous service day (offset secondsPerDay).
+type tripRun struct {
+	trip   *timetableTrip
+	offset int
+}
+
+// csa finds the earliest arrival journey from any of src stops to any of dst stops departing at or after
+// given time, using the Connection Scan Algorithm. Times are seconds since the journey day midnight.
+// Trips of the previous service day running past 24:00 are scanned along with the journey day's trips.
+// active reports whether a connection can be used on the service day of given offset.
+// defaultTransferTime is the time in seconds to transfer between stops of a station without a transfers.txt entry.
+func (t *timetable) csa(srcStops, dstStops []string, departure int, active func(c *connection, offset int) bool, defaultTransferTime int) ([]*timetableLeg, error) {
+	earliest := map[string]int{}             // earliest arrival time at every stop.
+	pointers := map[string]*journeyPointer{} // how every stop was reached at earliest arrival time.
+	boarded := map[tripRun]*connection{}     // connection where every reachable trip run was boarded.
+	arrivalAt := func(stop string) int {
+		if arrival, ok := earliest[stop]; ok {
+			return arrival
+		}
+		return math.MaxInt32
+	}
+
+	// relaxTransfers updates earliest arrival of stops reachable by a transfer from given stop.
+	relaxTransfers := func(from string) {
+		for to, d := range t.getTransfers(from, defaultTransferTime) {
+			if earliest[from]+d < arrivalAt(to) {
+				earliest[to] = earliest[from] + d
+				pointers[to] = &journeyPointer{transferFrom: from}
+			}
+		}
+	}
+
+	for _, stop := range srcStops {
+		earliest[stop] = departure
+	}
+	for _, stop := range srcStops {
+		relaxTransfers(stop)
+	}
+
+	bestArrival := func() int {
+		best := math.MaxInt32
+		for _, stop := range dstStops {
+			if arrival := arrivalAt(stop); arrival < best {
+				best = arrival
+			}
+		}
+		return best
+	}
+
+	// scan connections of both service days departing at or after departure, merged in order of departure.
+	previous, current := t.getConnectionsFrom(departure+secondsPerDay), t.getConnectionsFrom(departure)
+	for len(previous) > 0 || len(current) > 0 {
+		var c *connection
+		offset := 0
+		if len(current) == 0 || (len(previous) > 0 && previous[0].departure-secondsPerDay < current[0].departure) {
+			c, offset, previous = previous[0], secondsPerDay, previous[1:]
+		} else {
+			c, current = current[0], current[1:]
+		}
+		if !active(c, offset) {
+			continue
+		}
+		cDeparture, cArrival := c.departure-offset, c.arrival-offset
+		if cDeparture >= bestArrival() {
+			break // no later connection can improve arrival at destination.
+		}
+
+		run := tripRun{trip: c.trip, offset: offset}
+		if _, ok := boarded[run]; !ok && arrivalAt(c.fromStop) <= cDeparture {
+			boarded[run] = c
+		}
+		enter, ok := boarded[run]
+		if !ok || cArrival >= arrivalAt(c.toStop) {
+			continue
+		}
+
+		earliest[c.toStop] = cArrival
+		pointers[c.toStop] = &journeyPointer{enter: enter, exit: c, offset: offset}
+		relaxTransfers(c.toStop)
+	}
+
+	if bestArrival() == math.MaxInt32 {
+		return nil, ErrRouteNotFound
+	}
+
+	// walk back from the earliest reached destination stop to a source stop.
+	stop := dstStops[0]
+	for _, s := range dstStops {
+		if arrivalAt(s) < arrivalAt(stop) {
+			stop = s
+		}
+	}
+	legs := []*timetableLeg{}
+	for p, ok := pointers[stop]; ok; p, ok = pointers[stop] {
+		if p.enter == nil {
+			stop = p.transferFrom
+			continue
+		}
+		legs = append([]*timetableLeg{{
+			line:      p.enter.trip.line,
+			fromStop:  p.enter.fromStop,
+			toStop:    p.exit.toStop,
+			departure: p.enter.departure - p.offset,
+			arrival:   p.exit.arrival - p.offset,
+			stops:     t.getTripStops(p.enter, p.exit),
+			terminus:  t.getTripTerminus(p.enter.trip),
+		}}, legs...)
+		stop = p.enter.fromStop
+	}
+	return legs, nil
+}
+
+// getConnectionsFrom returns connections departing at or after given time, ordered by departure time.
+func (t *timetable) getConnectionsFrom(departure int) []*connection {
+	i := sort.Search(len(t.connections), func(i int) bool {
+		return t.connections[i].departure >= departure
+	})
+	return t.connections[i:]
+}
+
+// getTripStops returns ordered stop ids a trip passes between the from stop of enter connection and
+// the to stop of exit connection.
+func (t *timetable) getTripStops(enter, exit *connection) []string {
+	stops := []string{}
+	entered := false
+	for _, c := range t.trips[enter.trip] {
+		if c == exit {
+			break
+		}
+		entered = entered || c == enter
+		if entered {
+			stops = append(stops, c.toStop)
+		}
+	}
+	return stops
+}
+
+// getTripTerminus returns stop id of the last stop of given trip.
+func (t *timetable) getTripTerminus(trip *timetableTrip) string {
+	connections := t.trips[trip]
+	return connections[len(connections)-1].toStop
+}
+
+// getTransfers returns stops reachable by a transfer from given stop, mapped to the transfer time in seconds.
+// Every other stop of the same station is reachable, by default in defaultTransferTime.
+func (t *timetable) getTransfers(from string, defaultTransferTime int) map[string]int {
+	transfers := map[string]int{}
+	if stop, ok := t.stops[from]; ok {
+		for _, to := range t.stationStops[stop.name] {
+			if to != from {
+				transfers[to] = defaultTransferTime
+			}
+		}
+	}
+	for to, d := range t.transfers[from] {
+		transfers[to] = d
+	}
+	return transfers
+}
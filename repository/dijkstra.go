@@ -4,93 +4,130 @@ import (
 	"container/heap"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/rahulbharuka/train-route-finder/types"
 )
 
-// prevVertex objects stores previous vertex attributes.
-type prevVertex struct {
+// vertexState is a search state i.e. a station along with the train line used to reach it.
+// Keying the search by state lets a station be settled once per arriving line, so
+// interchange costs are applied optimally.
+type vertexState struct {
 	stationIdx int
 	line       string
+	trainLine  string // last train line taken in search order; it differs from line when walking
 }
 
-// dijkstra finds shortest path from src to dst using Dijkstra's algorithm.
-func (h *handlerImpl) dijkstra(adjMatrix adjacencyMatrix, src int, dst int, ht types.HourType, computeTimeCost bool) (int, map[int]*prevVertex, error) {
-	if _, ok := adjMatrix[src]; !ok {
-		return math.MaxInt32, nil, fmt.Errorf("Vertex %v does not exist", src)
+// next returns the state reached from state by travelling to given station on given train line, or walking.
+func (s vertexState) next(stationIdx int, line string) vertexState {
+	trainLine := line
+	if line == walkLine {
+		trainLine = s.trainLine
 	}
+	return vertexState{stationIdx: stationIdx, line: line, trainLine: trainLine}
+}
 
-	prevMap := make(map[int]*prevVertex)     // map to store previous node of a minHeapNode.
-	minHeap := minHeap{}                     // min heap to find unvisited vertext with min distance.
-	minHeapNodeMap := map[int]*minHeapNode{} // heap index to node map
+// dijkstra finds shortest path from src to dst using Dijkstra's algorithm over (station, line) states.
+// src carries the train line used to reach it, if any. It's used to charge an interchange at src.
+// startTime is the clock time at src. Every edge and interchange is charged by the hour type at the
+// clock time the rider reaches it. A zero startTime charges default cost. In a backward search, src is
+// the journey destination, startTime is the arrival time there, and the clock goes back in time.
+// The returned path is the states of the route, so it carries the train line taken to reach every station.
+func (h *handlerImpl) dijkstra(adjMatrix adjacencyMatrix, src vertexState, dst int, startTime time.Time, backward bool, mode types.RouteMode) (int, []vertexState, error) {
+	if _, ok := adjMatrix[src.stationIdx]; !ok {
+		return math.MaxInt32, nil, fmt.Errorf("Vertex %v does not exist", src.stationIdx)
+	}
 
-	// initialize the min-heap
-	i := 0
-	for stationIdx := range adjMatrix {
-		prevMap[stationIdx] = &prevVertex{stationIdx: -1}
-		if stationIdx != src {
-			n := &minHeapNode{stationIdx: stationIdx, dist: math.MaxInt32, idx: i}
-			minHeap = append(minHeap, n)
-			minHeapNodeMap[stationIdx] = n
-		} else {
-			n := &minHeapNode{stationIdx: stationIdx, dist: 0, idx: i}
-			minHeap = append(minHeap, n)
-			minHeapNodeMap[stationIdx] = n
-		}
-		i++
+	settled, prevMap := h.search(adjMatrix, src, dst, startTime, backward, mode, math.MaxInt32)
+	if last := settled[len(settled)-1]; last.state.stationIdx == dst {
+		return last.dist, h.prepareDijkstraPath(last.state, prevMap), nil
 	}
-	heap.Init(&minHeap)
+
+	// As every reachable state is visited without reaching 'dst', it is unreachable.
+	return math.MaxInt32, nil, ErrRouteNotFound
+}
+
+// search runs Dijkstra's algorithm from src until it settles a state of dst, or every reachable state within maxDist.
+// Passing noStation as dst explores every reachable state. It returns settled states' heap nodes in increasing
+// order of distance, along with the previous state of every state.
+func (h *handlerImpl) search(adjMatrix adjacencyMatrix, src vertexState, dst int, startTime time.Time, backward bool, mode types.RouteMode, maxDist int) ([]*minHeapNode, map[vertexState]vertexState) {
+	settled := []*minHeapNode{}                      // heap nodes of states whose shortest distance is final.
+	prevMap := map[vertexState]vertexState{}         // map to store previous state of a state.
+	minHeap := minHeap{}                             // min heap to find unvisited state with min distance.
+	minHeapNodeMap := map[vertexState]*minHeapNode{} // state to heap node map
+	visited := map[vertexState]bool{}                // states whose shortest distance is final.
+
+	n := &minHeapNode{state: src, dist: 0}
+	heap.Push(&minHeap, n)
+	minHeapNodeMap[src] = n
 
 	// now run Dijkstra's algorithm
 	for minHeap.Len() != 0 {
 		fromNode := heap.Pop(&minHeap).(*minHeapNode)
-		if fromNode.dist == math.MaxInt32 {
-			// As current minHeapNode is unreachable, 'dst' will also be unreachable. So break early.
-			return math.MaxInt32, nil, ErrRouteNotFound
+		if fromNode.dist > maxDist {
+			break // every remaining state is farther than max distance.
 		}
+		from := fromNode.state
+		visited[from] = true
+		settled = append(settled, fromNode)
 
-		from := fromNode.stationIdx
-		if from == dst {
+		if from.stationIdx == dst {
 			// route found. so break early.
-			return fromNode.dist, prevMap, nil
+			break
 		}
 
-		// update distance for every vertex directly reachable from current vertex.
-		for to, edge := range adjMatrix[from] {
+		// update distance for every state directly reachable from current state.
+		ht := getHourTypeAt(startTime, fromNode.elapsed, backward)
+		for to, edge := range adjMatrix[from.stationIdx] {
 			if edge.disabled {
 				continue // edge is disabled; so skip it.
 			}
-			weight := edge.weight.defaults
-			interchangeCost := 0
-			nextLine := h.getTrainLine(from, to)
-
-			if computeTimeCost {
-				weight = h.getEdgeWeight(adjMatrix, from, to, ht)
-
-				if prevMap[from].line != "" && nextLine != prevMap[from].line {
-					interchangeCost = interchangeCostMap[ht]
+			for nextLine, w := range edge.lines {
+				if !w.isInService(ht) {
+					continue // train line is not in service; so skip it.
+				}
+				toState := from.next(to, nextLine)
+				if visited[toState] {
+					continue
 				}
-			}
 
-			toNode := minHeapNodeMap[to]
-			newDist := fromNode.dist + weight + interchangeCost
-			if newDist < toNode.dist {
-				toNode.dist = newDist
-				heap.Fix(&minHeap, toNode.idx)
-				prevMap[to].stationIdx = from
-				prevMap[to].line = nextLine
+				newDist := fromNode.dist + h.getTravelCost(adjMatrix, from, to, nextLine, ht, backward, mode)
+				newElapsed := fromNode.elapsed + h.getTravelCost(adjMatrix, from, to, nextLine, ht, backward, types.RMTime)
+				if backward && to == dst {
+					// backward search reaches journey source, where rider boards the first train.
+					newElapsed = newElapsed + getWaitTime(nextLine, ht)
+					if mode != types.RMStops {
+						newDist = newDist + getWaitTime(nextLine, ht)
+					}
+				}
+				newInterchanges := fromNode.interchanges
+				if isInterchange(from.trainLine, nextLine) {
+					newInterchanges++
+				}
+				toNode, ok := minHeapNodeMap[toState]
+				if !ok {
+					toNode = &minHeapNode{state: toState, dist: newDist, elapsed: newElapsed, interchanges: newInterchanges}
+					heap.Push(&minHeap, toNode)
+					minHeapNodeMap[toState] = toNode
+					prevMap[toState] = from
+				} else if newDist < toNode.dist || (newDist == toNode.dist && newInterchanges < toNode.interchanges) {
+					toNode.dist = newDist
+					toNode.elapsed = newElapsed
+					toNode.interchanges = newInterchanges
+					heap.Fix(&minHeap, toNode.idx)
+					prevMap[toState] = from
+				}
 			}
 		}
 	}
-
-	return minHeapNodeMap[dst].dist, prevMap, nil
+	return settled, prevMap
 }
 
-// prepareDijkstraPath preapares path to passed destination.
-func (h *handlerImpl) prepareDijkstraPath(dst int, prevMap map[int]*prevVertex, route *[]int) error {
-	if prevMap[dst].stationIdx >= 0 {
-		h.prepareDijkstraPath(prevMap[dst].stationIdx, prevMap, route)
+// prepareDijkstraPath preapares path of states to passed destination state.
+func (h *handlerImpl) prepareDijkstraPath(dst vertexState, prevMap map[vertexState]vertexState) []vertexState {
+	route := []vertexState{}
+	for state, ok := dst, true; ok; state, ok = prevMap[state] {
+		route = append([]vertexState{state}, route...)
 	}
-	*route = append(*route, dst)
-	return nil
+	return route
 }
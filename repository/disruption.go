@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	disruptionMap   = map[string]*Disruption{} // maps disruption id to active or scheduled disruption.
+	disruptionID    int                        // id of the last added disruption.
+	disruptionMutex sync.RWMutex               // guards disruptionMap and railNetworkAdjacencyMatrix.
+)
+
+const week = 7 * 24 * time.Hour
+
+// Disruption is a closure of a station, a train line, or a segment between two adjacent stations.
+// A segment closure closes every train line (and walking link) between the stations, unless a train line is given.
+// A disruption with start and end time is a scheduled closure, which is active only during its window.
+type Disruption struct {
+	ID      string `json:"id"`
+	Station string `json:"station,omitempty"` // station name to close
+	Line    string `json:"line,omitempty"`    // train line code to close, or to close on the segment only
+	From    string `json:"from,omitempty"`    // station name at one end of the segment to close
+	To      string `json:"to,omitempty"`      // station name at the other end of the segment to close
+	Start   string `json:"start,omitempty"`   // start time of scheduled closure in YYYY-MM-DDTHH:MM format. optional
+	End     string `json:"end,omitempty"`     // end time of scheduled closure in YYYY-MM-DDTHH:MM format. optional
+	Weekly  bool   `json:"weekly,omitempty"`  // whether scheduled closure repeats every week from its start
+	Reason  string `json:"reason,omitempty"`  // description of the disruption. optional
+
+	start time.Time // parsed start time of scheduled closure
+	end   time.Time // parsed end time of scheduled closure
+}
+
+// AddDisruption validates and activates given disruption. Later routes go around it.
+func (h *handlerImpl) AddDisruption(d *Disruption) (*Disruption, error) {
+	return addDisruption(d)
+}
+
+// RemoveDisruption clears the disruption with given id.
+func (h *handlerImpl) RemoveDisruption(id string) error {
+	disruptionMutex.Lock()
+	defer disruptionMutex.Unlock()
+
+	if _, ok := disruptionMap[id]; !ok {
+		return ErrDisruptionNotFound
+	}
+	delete(disruptionMap, id)
+	railNetworkAdjacencyMatrix = createDisruptedAdjacencyMatrix(time.Time{}, getDisruptions(time.Time{}))
+	return nil
+}
+
+// ListDisruptions returns active and scheduled disruptions in the order they were added.
+func (h *handlerImpl) ListDisruptions() []*Disruption {
+	disruptionMutex.RLock()
+	defer disruptionMutex.RUnlock()
+
+	disruptions := make([]*Disruption, 0, len(disruptionMap))
+	for _, d := range disruptionMap {
+		disruption := *d
+		disruptions = append(disruptions, &disruption)
+	}
+	sortDisruptions(disruptions)
+	return disruptions
+}
+
+// addDisruption validates and adds given disruption, and applies it to rail network adjacency matrix.
+func addDisruption(d *Disruption) (*Disruption, error) {
+	disruption := *d
+	if err := disruption.validate(); err != nil {
+		return nil, err
+	}
+
+	disruptionMutex.Lock()
+	defer disruptionMutex.Unlock()
+
+	disruptionID++
+	disruption.ID = strconv.Itoa(disruptionID)
+	disruptionMap[disruption.ID] = &disruption
+	railNetworkAdjacencyMatrix = createDisruptedAdjacencyMatrix(time.Time{}, getDisruptions(time.Time{}))
+	return &disruption, nil
+}
+
+// validate checks that disruption closes exactly one of a station, a train line or a segment between adjacent stations,
+// and that scheduled closure window is valid.
+func (d *Disruption) validate() error {
+	switch {
+	case d.Station != "" && d.Line == "" && d.From == "" && d.To == "":
+		if _, ok := stationNameMap[d.Station]; !ok {
+			log.Printf("invalid station %v to close", d.Station)
+			return ErrInvalidRequest
+		}
+
+	case d.Station == "" && d.Line != "" && d.From == "" && d.To == "":
+		if _, ok := trainLineMap[d.Line]; !ok {
+			log.Printf("invalid train line %v to close", d.Line)
+			return ErrInvalidRequest
+		}
+
+	case d.Station == "" && d.From != "" && d.To != "":
+		from, ok1 := stationNameMap[d.From]
+		to, ok2 := stationNameMap[d.To]
+		if !ok1 || !ok2 {
+			log.Printf("invalid segment from %v to %v to close", d.From, d.To)
+			return ErrInvalidRequest
+		}
+
+		// stations must be adjacent when every line-station is opened.
+		lines := getSegmentLines(from, to)
+		if len(lines) == 0 {
+			log.Printf("stations %v and %v are not adjacent", d.From, d.To)
+			return ErrInvalidRequest
+		}
+		if d.Line != "" && !lines[d.Line] {
+			log.Printf("stations %v and %v are not adjacent on train line %v", d.From, d.To, d.Line)
+			return ErrInvalidRequest
+		}
+
+	default:
+		log.Println("disruption must close a station, a train line or a segment")
+		return ErrInvalidRequest
+	}
+
+	if d.Start == "" && d.End == "" && !d.Weekly {
+		return nil
+	}
+
+	var err1, err2 error
+	d.start, err1 = time.Parse(timeFormat, d.Start)
+	d.end, err2 = time.Parse(timeFormat, d.End)
+	if err1 != nil || err2 != nil || !d.end.After(d.start) || (d.Weekly && d.end.Sub(d.start) > week) {
+		log.Printf("invalid closure window from %v to %v", d.Start, d.End)
+		return ErrInvalidRequest
+	}
+	return nil
+}
+
+// getSegmentLines returns train lines (and walking link) connecting two stations which are adjacent when every
+// line-station is opened. It's empty if stations are not adjacent.
+func getSegmentLines(from, to *station) map[string]bool {
+	lines := map[string]bool{}
+	for _, stationCode := range from.codes {
+		lineCode := stationCode[:2]
+		stationCodes, i := trainLineMap[lineCode], lineStationMap[stationCode].lineStationIdx
+		for _, j := range []int{i - 1, i + 1} {
+			if j >= 0 && j < len(stationCodes) && lineStationMap[stationCodes[j]].name == to.name {
+				lines[lineCode] = true
+			}
+		}
+	}
+	if _, ok := walkingLinkMap[[2]int{from.idx, to.idx}]; ok {
+		lines[walkLine] = true
+	}
+	return lines
+}
+
+// isScheduled checks whether disruption is a scheduled closure.
+func (d *Disruption) isScheduled() bool {
+	return !d.start.IsZero()
+}
+
+// isActive checks whether disruption is active at given time. Disruption without window is always active.
+// Scheduled closure is never active at zero time.
+func (d *Disruption) isActive(at time.Time) bool {
+	if !d.isScheduled() {
+		return true
+	}
+	if at.IsZero() || at.Before(d.start) {
+		return false
+	}
+
+	offset := at.Sub(d.start)
+	if d.Weekly {
+		offset = offset % week
+	}
+	return offset < d.end.Sub(d.start)
+}
+
+// closes checks whether disruption closes given train line between two adjacent stations.
+func (d *Disruption) closes(from, to, lineCode string) bool {
+	switch {
+	case d.Station != "":
+		return d.Station == from || d.Station == to
+	case d.From != "":
+		return ((d.From == from && d.To == to) || (d.From == to && d.To == from)) && (d.Line == "" || d.Line == lineCode)
+	}
+	return d.Line == lineCode
+}
+
+// getDisruptions returns disruptions active at given time in the order they were added.
+// At zero time, only disruptions without window are active. Caller must hold disruptionMutex.
+func getDisruptions(at time.Time) []*Disruption {
+	disruptions := []*Disruption{}
+	for _, d := range disruptionMap {
+		if d.isActive(at) {
+			disruptions = append(disruptions, d)
+		}
+	}
+	sortDisruptions(disruptions)
+	return disruptions
+}
+
+// getActiveClosures returns scheduled closures active at given time.
+func getActiveClosures(at time.Time) []*Disruption {
+	disruptionMutex.RLock()
+	defer disruptionMutex.RUnlock()
+
+	closures := []*Disruption{}
+	for _, d := range getDisruptions(at) {
+		if d.isScheduled() {
+			closure := *d
+			closures = append(closures, &closure)
+		}
+	}
+	return closures
+}
+
+// sortDisruptions sorts disruptions by id i.e. in the order they were added.
+func sortDisruptions(disruptions []*Disruption) {
+	sort.Slice(disruptions, func(i, j int) bool {
+		idI, _ := strconv.Atoi(disruptions[i].ID)
+		idJ, _ := strconv.Atoi(disruptions[j].ID)
+		return idI < idJ
+	})
+}
+
+// isClosed checks whether any of given disruptions closes given train line between two adjacent stations.
+func isClosed(disruptions []*Disruption, from, to, lineCode string) bool {
+	for _, d := range disruptions {
+		if d.closes(from, to, lineCode) {
+			return true
+		}
+	}
+	return false
+}
+
+// createDisruptedAdjacencyMatrix creates adjacency matrix of the rail network as on given date,
+// leaving out every train line closed by given disruptions.
+func createDisruptedAdjacencyMatrix(asOf time.Time, disruptions []*Disruption) adjacencyMatrix {
+	adjMatrix := createAdjacencyMatrix(asOf)
+	for from := range adjMatrix {
+		for to, e := range adjMatrix[from] {
+			for lineCode := range e.lines {
+				if isClosed(disruptions, stationIndexMap[from].name, stationIndexMap[to].name, lineCode) {
+					delete(e.lines, lineCode)
+				}
+			}
+			if len(e.lines) == 0 {
+				delete(adjMatrix[from], to)
+			}
+		}
+	}
+	return adjMatrix
+}
+
+// createNetworkAdjacencyMatrix returns adjacency matrix of the rail network as on given date,
+// with disruptions active at that time applied. A zero date returns a copy of railNetworkAdjacencyMatrix.
+func createNetworkAdjacencyMatrix(asOf time.Time) adjacencyMatrix {
+	if asOf.IsZero() {
+		return createAdjacencyMatrixCopy()
+	}
+
+	disruptionMutex.RLock()
+	defer disruptionMutex.RUnlock()
+	return createDisruptedAdjacencyMatrix(asOf, getDisruptions(asOf))
+}
+
+// createUnscheduledAdjacencyMatrix returns adjacency matrix of the rail network as on given date,
+// with disruptions applied except scheduled closures.
+func createUnscheduledAdjacencyMatrix(asOf time.Time) adjacencyMatrix {
+	disruptionMutex.RLock()
+	defer disruptionMutex.RUnlock()
+	return createDisruptedAdjacencyMatrix(asOf, getDisruptions(time.Time{}))
+}
+
+// flagClosures sets closures on every route which differs (in stations or train lines) from the route of the same rank
+// found without scheduled closures. Only the closures blocking routes found without them are set.
+func (h *handlerImpl) flagClosures(adjMatrix adjacencyMatrix, routes []*Route, paths [][]vertexState, closures []*Disruption, src, dst int, avoidStations []int, query *RouteQuery) {
+	journeyTime, backward := query.searchTime()
+	unclosedAdjMatrix := createUnscheduledAdjacencyMatrix(journeyTime)
+	removeStations(unclosedAdjMatrix, avoidStations)
+	removeLines(unclosedAdjMatrix, query.AvoidLines)
+
+	_, unclosedPaths, _, err := h.findAlternatives(unclosedAdjMatrix, src, dst, len(paths), journeyTime, backward, query.Mode)
+	if err != nil {
+		return
+	}
+
+	blocking := h.getBlockingClosures(closures, unclosedPaths)
+	if len(blocking) == 0 {
+		return
+	}
+	for i, route := range routes {
+		if i >= len(unclosedPaths) {
+			route.Closures = blocking
+			continue
+		}
+		pathLines := strings.Join(getPathLines(paths[i]), ",")
+		unclosedPathLines := strings.Join(getPathLines(unclosedPaths[i]), ",")
+		if !isSamePath(paths[i], unclosedPaths[i]) || pathLines != unclosedPathLines {
+			route.Closures = blocking
+		}
+	}
+}
+
+// getBlockingClosures returns given closures which close any hop of given paths.
+func (h *handlerImpl) getBlockingClosures(closures []*Disruption, paths [][]vertexState) []*Disruption {
+	blocking := []*Disruption{}
+	for _, closure := range closures {
+		blocked := false
+		for _, path := range paths {
+			for i := 0; i+1 < len(path) && !blocked; i++ {
+				blocked = closure.closes(stationIndexMap[path[i].stationIdx].name, stationIndexMap[path[i+1].stationIdx].name, path[i+1].line)
+			}
+		}
+		if blocked {
+			blocking = append(blocking, closure)
+		}
+	}
+	return blocking
+}
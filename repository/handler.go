@@ -4,7 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/rahulbharuka/train-route-finder/types"
+)
+
+const (
+	dateFormat = "2 January 2006"   // date format used in rail network data file.
+	timeFormat = "2006-01-02T15:04" // time format used in route response.
 )
 
 var (
@@ -12,73 +22,446 @@ var (
 	ErrRouteNotFound = errors.New("no route exist")
 	// ErrInvalidRequest ...
 	ErrInvalidRequest = errors.New("invalid request")
+	// ErrDisruptionNotFound ...
+	ErrDisruptionNotFound = errors.New("disruption not found")
 )
 
+// RouteNotFoundError is returned when no route exist along with the reason for it.
+type RouteNotFoundError struct {
+	Reason string
+}
+
+func (e *RouteNotFoundError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrRouteNotFound, e.Reason)
+}
+
 // Route is the route response object
 type Route struct {
+	Source       string          `json:"source"`              // resolved source station name
+	Destination  string          `json:"destination"`         // resolved destination station name
+	Departure    string          `json:"departure,omitempty"` // journey start time, or latest departure time for arrival time
+	Heading      string          `json:"heading"`
+	Steps        string          `json:"steps"`
+	Cost         int             `json:"cost"` // route cost as in heading e.g. travel time in time and pareto modes
+	Stops        int             `json:"stops"`
+	Interchanges int             `json:"interchanges"`
+	Legs         []*RouteLeg     `json:"legs"`
+	Segments     []*RouteSegment `json:"segments,omitempty"`
+	Closures     []*Disruption   `json:"closures,omitempty"` // scheduled closures which changed the route
+}
+
+// RouteLeg is the part of a route travelled on a single train line, or walked between stations.
+// Travel time, waiting time, interchange cost, departure and arrival time are set only if journey time is set;
+// walking time is always set.
+type RouteLeg struct {
+	Line                 string   `json:"line"`               // train line code, or WALK
+	Terminus             string   `json:"terminus,omitempty"` // last station of the train line in the direction of travel
+	BoardStation         string   `json:"boardStation"`
+	BoardCode            string   `json:"boardCode,omitempty"`
+	AlightStation        string   `json:"alightStation"`
+	AlightCode           string   `json:"alightCode,omitempty"`
+	IntermediateStations []string `json:"intermediateStations"` // ordered stations passed between board and alight station
+	Stops                int      `json:"stops"`
+	Duration             int      `json:"duration"`            // travel (or walking) time of the leg
+	WaitTime             int      `json:"waitTime"`            // expected waiting time for the train
+	InterchangeCost      int      `json:"interchangeCost"`     // cost of changing to the leg's train line
+	Departure            string   `json:"departure,omitempty"` // departure time from board station
+	Arrival              string   `json:"arrival,omitempty"`   // arrival time at alight station
+}
+
+// RouteSegment is the part of a route between two consecutive stops of a route with via stations.
+type RouteSegment struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
 	Heading string `json:"heading"`
 	Steps   string `json:"steps"`
 }
 
+// RouteQuery is the route request object
+type RouteQuery struct {
+	Source        string          // source station name or code
+	Destination   string          // destination station name or code
+	Via           []string        // ordered station names or codes which route must pass through
+	AvoidStations []string        // station names or codes which route must not pass through
+	AvoidLines    []string        // train line codes which route must not use
+	JourneyTime   time.Time       // journey start time. optional
+	ArriveBy      time.Time       // journey arrival time. optional, exclusive with journey start time
+	Mode          types.RouteMode // route ranking mode
+	K             int             // number of routes to return, capped by max routes. optional, defaults to max routes
+	Languages     []string        // preferred languages of route instructions, most preferred first. optional
+}
+
 // Handler is the repository handler interface
 type Handler interface {
-	FindRoutes(source string, destination string, journeyTime time.Time, computeTimeCost bool) ([]*Route, error)
+	FindRoutes(query *RouteQuery) ([]*Route, bool, error)
+	FindReachable(query *ReachableQuery) ([]*ReachableStation, error)
+	FindMatrix(query *MatrixQuery) (*TravelMatrix, error)
+	AddDisruption(d *Disruption) (*Disruption, error)
+	RemoveDisruption(id string) error
+	ListDisruptions() []*Disruption
+	MaxRoutes() int
+	SearchStations(query string) ([]*StationMatch, error)
 }
 
 // handlerImpl is a implementation of Handler interface
 type handlerImpl struct{}
 
 // GetHandler initializes and returns the repository layer handler.
+// If timetable is configured, routes are found on the timetable.
 func GetHandler() Handler {
+	if railTimetable != nil {
+		return &timetableHandlerImpl{
+			handlerImpl: &handlerImpl{},
+			timetable:   railTimetable,
+		}
+	}
 	return &handlerImpl{}
 }
 
+// MaxRoutes returns the max number of routes returned per route query.
+func (h *handlerImpl) MaxRoutes() int {
+	return topK
+}
+
 // FindRoutes find shortest top-k routes from source to destionation.
-func (h *handlerImpl) FindRoutes(source string, destination string, journeyTime time.Time, computeTimeCost bool) ([]*Route, error) {
-	srcStation, ok1 := stationNameMap[source]
-	dstStation, ok2 := stationNameMap[destination]
-	if !ok1 || !ok2 {
-		log.Println("invalid source or destination station")
-		return nil, ErrInvalidRequest
+// k is capped by max routes, and fewer routes are returned if fewer routes exist. It also returns whether
+// fewer than k routes exist; it's always false for pareto and via routes, which don't use k.
+// If journeyTime is set, routes only use stations opened and train lines in service at that time.
+// Travel time and pareto modes require journeyTime.
+// If via stations are set, it returns a single route chaining shortest routes between consecutive stops.
+// If arriveBy is set, routes are searched backwards from destination and carry their latest departure time.
+func (h *handlerImpl) FindRoutes(query *RouteQuery) ([]*Route, bool, error) {
+	journeyTime, backward := query.searchTime()
+	mode, lang := query.Mode, query.language()
+
+	if !query.JourneyTime.IsZero() && !query.ArriveBy.IsZero() {
+		log.Println("journey start time and arrival time cannot be set together")
+		return nil, false, ErrInvalidRequest
+	}
+
+	stops := []*station{}
+	for _, name := range append(append([]string{query.Source}, query.Via...), query.Destination) {
+		s, ok := getStation(name)
+		if !ok {
+			return nil, false, h.stationNotFound(name)
+		}
+		if len(stops) > 0 && stops[len(stops)-1] == s {
+			log.Println("invalid source, via or destination station")
+			return nil, false, ErrInvalidRequest
+		}
+		stops = append(stops, s)
 	}
+	srcStation, dstStation := stops[0], stops[len(stops)-1]
 
-	dist, prev, err := h.yen(createAdjacencyMatrixCopy(), srcStation.idx, dstStation.idx, topK, journeyTime, computeTimeCost)
+	avoidStations := []int{}
+	for _, name := range query.AvoidStations {
+		s, ok := getStation(name)
+		if !ok {
+			return nil, false, h.stationNotFound(name)
+		}
+		for _, stop := range stops {
+			if s == stop {
+				log.Printf("station %v to avoid is a stop of the route", name)
+				return nil, false, ErrInvalidRequest
+			}
+		}
+		avoidStations = append(avoidStations, s.idx)
+	}
+
+	for _, lineCode := range query.AvoidLines {
+		if _, ok := trainLineMap[lineCode]; !ok {
+			log.Printf("invalid train line %v to avoid", lineCode)
+			return nil, false, ErrInvalidRequest
+		}
+	}
+
+	if mode == types.RMInvalid || ((mode == types.RMTime || mode == types.RMPareto) && journeyTime.IsZero()) {
+		log.Printf("invalid route mode %v", mode)
+		return nil, false, ErrInvalidRequest
+	}
+
+	if mode == types.RMPareto && len(query.Via) > 0 {
+		log.Println("via stations are not supported in pareto mode")
+		return nil, false, ErrInvalidRequest
+	}
+
+	if backward && (mode == types.RMStops || mode == types.RMPareto || len(query.Via) > 0) {
+		log.Printf("arrival time is not supported in route mode %v with via stations %v", mode, query.Via)
+		return nil, false, ErrInvalidRequest
+	}
+
+	if !journeyTime.IsZero() {
+		for _, s := range stops {
+			if !s.isOpen(journeyTime) {
+				log.Printf("station %v is not opened by %v", s.name, journeyTime)
+				return nil, false, &RouteNotFoundError{
+					Reason: fmt.Sprintf("%v station opens on %v", s.name, s.openingDate().Format(dateFormat)),
+				}
+			}
+		}
+	}
+
+	adjMatrix := createNetworkAdjacencyMatrix(journeyTime)
+	removeStations(adjMatrix, avoidStations)
+	removeLines(adjMatrix, query.AvoidLines)
+
+	if mode == types.RMPareto {
+		routes, err := h.findParetoRoutes(adjMatrix, srcStation, dstStation, query)
+		return routes, false, err
+	}
+
+	if len(query.Via) > 0 {
+		routes, err := h.findViaRoute(adjMatrix, stops, query)
+		return routes, false, err
+	}
+
+	// backward search runs from destination to source.
+	src, dst := srcStation.idx, dstStation.idx
+	if backward {
+		src, dst = dst, src
+	}
+
+	dist, prev, fewer, err := h.findAlternatives(adjMatrix, src, dst, query.routeCount(), journeyTime, backward, mode)
+	if err == ErrRouteNotFound {
+		err = h.explainRouteNotFound(src, dst, query)
+	}
 	if err != nil {
 		log.Printf("failed to find route from %v to dst %v, err: %v", srcStation.name, dstStation.name, err)
-		return nil, err
+		return nil, false, err
 	}
 
+	// preapare response
 	resp := make([]*Route, len(dist))
+	for i := 0; i < len(dist); i++ {
+		path, departure, cost := prev[i], journeyTime, dist[i]
+		if backward {
+			// route is charged forward from its departure, like the legs the rider travels.
+			travelTime := h.getPathWeight(adjMatrix, path, journeyTime, backward, types.RMTime)
+			path = reversePath(path)
+			departure = h.getLatestDeparture(adjMatrix, path, addMinutes(journeyTime, travelTime, backward), journeyTime)
+			cost = h.getPathWeight(adjMatrix, path, departure, false, mode)
+		}
+		heading := h.prepareRouteHeading(cost, h.getPathWaitTime(adjMatrix, path, departure), journeyTime, mode, lang)
+		if backward {
+			heading = joinMessages(lang, message(lang, msgLatestDeparture, departure.Format(timeFormat)), heading)
+		}
+		resp[i] = h.prepareRoute(adjMatrix, path, departure, getRouteCost(cost, mode), lang)
+		resp[i].Heading = heading
+	}
 
-	var headingTemplate string
-	if computeTimeCost {
-		headingTemplate = "Expected Travel time: %v"
-	} else {
-		headingTemplate = "Number of stops to destination: %v"
+	// flag routes changed by scheduled closures active at journey time.
+	if closures := getActiveClosures(journeyTime); len(closures) > 0 {
+		h.flagClosures(adjMatrix, resp, prev, closures, src, dst, avoidStations, query)
 	}
-	// preapare response
-	for i := 0; i < len(dist); i++ {
-		resp[i] = &Route{
-			Heading: fmt.Sprintf(headingTemplate, dist[i]),
-			Steps:   h.prepareRouteSteps(prev[i]),
+
+	return resp, fewer, nil
+}
+
+// getLatestDeparture returns the latest departure time, not later than given departure time found by a backward
+// search, at which given path arrives by given arrival time. Hops are charged by the hour type at the time
+// the rider reaches them, so a path crossing an hour type boundary may need to depart earlier than the backward
+// search found. If path never arrives in time within a day, it returns given departure time.
+func (h *handlerImpl) getLatestDeparture(adjMatrix adjacencyMatrix, path []vertexState, departure, arrival time.Time) time.Time {
+	for t := departure; t.After(arrival.Add(-24 * time.Hour)); t = addMinutes(t, 1, true) {
+		travelTime := h.getPathWeight(adjMatrix, path, t, false, types.RMTime)
+		if travelTime != math.MaxInt32 && !addMinutes(t, travelTime, false).After(arrival) {
+			return t
 		}
 	}
+	return departure
+}
 
+// prepareRouteHeading returns route heading with route cost for given mode in given language.
+// Expected waiting time for trains, which is part of travel time, is reported separately if any.
+func (h *handlerImpl) prepareRouteHeading(dist, waitTime int, journeyTime time.Time, mode types.RouteMode, lang string) string {
+	var parts []string
+	switch {
+	case mode == types.RMTime:
+		parts = []string{message(lang, msgTravelTime, dist)}
+	case mode == types.RMTransfers && !journeyTime.IsZero():
+		parts = []string{message(lang, msgInterchanges, dist/interchangePenalty), message(lang, msgTravelTime, dist%interchangePenalty)}
+	case mode == types.RMTransfers:
+		return joinMessages(lang, message(lang, msgInterchanges, dist/interchangePenalty), message(lang, msgStops, dist%interchangePenalty))
+	default:
+		return message(lang, msgStops, dist)
+	}
+
+	if waitTime > 0 {
+		parts = append(parts, message(lang, msgWaitingTime, waitTime))
+	}
+	return joinMessages(lang, parts...)
+}
+
+// findParetoRoutes finds every pareto-optimal route from source to destination on travel time, stops and interchanges.
+func (h *handlerImpl) findParetoRoutes(adjMatrix adjacencyMatrix, srcStation, dstStation *station, query *RouteQuery) ([]*Route, error) {
+	labels, err := h.pareto(adjMatrix, srcStation.idx, dstStation.idx, query.JourneyTime)
+	if err == ErrRouteNotFound {
+		err = h.explainRouteNotFound(srcStation.idx, dstStation.idx, query)
+	}
+	if err != nil {
+		log.Printf("failed to find route from %v to dst %v, err: %v", srcStation.name, dstStation.name, err)
+		return nil, err
+	}
+
+	// preapare response
+	lang := query.language()
+	resp := make([]*Route, len(labels))
+	for i, l := range labels {
+		parts := []string{message(lang, msgTravelTime, l.time), message(lang, msgStops, l.stops), message(lang, msgInterchanges, l.interchanges)}
+		if waitTime := h.getPathWaitTime(adjMatrix, l.path(), query.JourneyTime); waitTime > 0 {
+			parts = append(parts, message(lang, msgWaitingTime, waitTime))
+		}
+		resp[i] = h.prepareRoute(adjMatrix, l.path(), query.JourneyTime, l.time, lang)
+		heading := joinMessages(lang, parts...)
+		resp[i].Heading = heading
+	}
 	return resp, nil
 }
 
+// explainRouteNotFound returns the reason why no route exist from src to dst for given route query.
+// For arrival time queries, src is the journey destination.
+func (h *handlerImpl) explainRouteNotFound(src, dst int, query *RouteQuery) error {
+	journeyTime, backward := query.searchTime()
+	adjMatrix := createNetworkAdjacencyMatrix(journeyTime)
+
+	// check whether a route exist without avoiding any station or train line.
+	if len(query.AvoidStations) > 0 || len(query.AvoidLines) > 0 {
+		if _, _, err := h.dijkstra(adjMatrix, vertexState{stationIdx: src}, dst, journeyTime, backward, types.RMStops); err == nil {
+			return &RouteNotFoundError{
+				Reason: "route depends on avoided stations or train lines",
+			}
+		}
+	}
+
+	// check whether a route exist without any disruption.
+	if len(h.ListDisruptions()) > 0 {
+		if _, _, err := h.dijkstra(createAdjacencyMatrix(journeyTime), vertexState{stationIdx: src}, dst, journeyTime, backward, types.RMStops); err == nil {
+			return &RouteNotFoundError{
+				Reason: "route depends on disrupted stations or train lines",
+			}
+		}
+	}
+
+	if journeyTime.IsZero() {
+		return ErrRouteNotFound
+	}
+
+	// check whether a route exist when every train line is in service.
+	if _, path, err := h.dijkstra(adjMatrix, vertexState{stationIdx: src}, dst, time.Time{}, backward, types.RMStops); err == nil {
+		ht := h.getClosureHourType(adjMatrix, path, journeyTime, backward)
+		closedLines := getClosedPathLines(adjMatrix, path, ht)
+		format := "%v lines are not in service during %v hours"
+		if len(closedLines) == 1 {
+			format = "%v line is not in service during %v hours"
+		}
+		return &RouteNotFoundError{
+			Reason: fmt.Sprintf(format, strings.Join(closedLines, ", "), ht),
+		}
+	}
+
+	// check whether a route exist once all line-stations are opened.
+	if _, _, err := h.dijkstra(createAdjacencyMatrixCopy(), vertexState{stationIdx: src}, dst, time.Time{}, backward, types.RMStops); err == nil {
+		return &RouteNotFoundError{
+			Reason: fmt.Sprintf("route depends on stations not opened by %v", journeyTime.Format(dateFormat)),
+		}
+	}
+	return ErrRouteNotFound
+}
+
+// getClosureHourType returns hour type at the clock time given path first reaches a train line not in service.
+// If path never reaches such train line, it returns hour type at start time.
+func (h *handlerImpl) getClosureHourType(adjMatrix adjacencyMatrix, path []vertexState, startTime time.Time, backward bool) types.HourType {
+	elapsed := 0
+	for i := 0; i+1 < len(path); i++ {
+		from, to := path[i], path[i+1]
+		ht := getHourTypeAt(startTime, elapsed, backward)
+		if !adjMatrix[from.stationIdx][to.stationIdx].lines[to.line].isInService(ht) {
+			return ht
+		}
+		elapsed = elapsed + h.getTravelCost(adjMatrix, from, to.stationIdx, to.line, ht, backward, types.RMTime)
+	}
+	return types.GetHourType(startTime)
+}
+
+// getClosedPathLines returns sorted train line codes of given path which are not in service during given hour type.
+func getClosedPathLines(adjMatrix adjacencyMatrix, path []vertexState, ht types.HourType) []string {
+	closed := map[string]bool{}
+	lines := []string{}
+	for i := 0; i+1 < len(path); i++ {
+		from, to := path[i], path[i+1]
+		if !closed[to.line] && !adjMatrix[from.stationIdx][to.stationIdx].lines[to.line].isInService(ht) {
+			closed[to.line] = true
+			lines = append(lines, to.line)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// routeCount returns number of routes to return for the query.
+func (q *RouteQuery) routeCount() int {
+	if q.K <= 0 || q.K > topK {
+		return topK
+	}
+	return q.K
+}
+
+// language returns the language of route instructions for the query.
+func (q *RouteQuery) language() string {
+	return getLanguage(q.Languages)
+}
+
+// searchTime returns the clock time route search starts at, and whether the search goes back in time from arrival.
+func (q *RouteQuery) searchTime() (time.Time, bool) {
+	if !q.ArriveBy.IsZero() {
+		return q.ArriveBy, true
+	}
+	return q.JourneyTime, false
+}
+
 // createAdjacencyMatrix returns a deep copy (except edge weights) of adjacency matrix.
 func createAdjacencyMatrixCopy() adjacencyMatrix {
+	disruptionMutex.RLock()
+	defer disruptionMutex.RUnlock()
+
 	adjCopy := make(adjacencyMatrix)
 	for from, adjacency := range railNetworkAdjacencyMatrix {
 		adjMap := make(map[int]*edge)
 		for to, e := range adjacency {
+			lines := make(map[string]*weight, len(e.lines))
+			for lineCode, w := range e.lines {
+				lines[lineCode] = w // edge weight is not modified during route calculation. So separate copy is not needed.
+			}
 			adjMap[to] = &edge{
 				disabled: e.disabled,
-				weight:   e.weight, // edge weight is not modified during route calculation. So separate copy is not needed.
+				lines:    lines,
 			}
 		}
 		adjCopy[from] = adjMap
 	}
 	return adjCopy
 }
+
+// removeStations removes all edges from and to given stations, so no route passes through them.
+func removeStations(adjMatrix adjacencyMatrix, stations []int) {
+	for _, from := range stations {
+		for to := range adjMatrix[from] {
+			delete(adjMatrix[to], from)
+		}
+		adjMatrix[from] = map[int]*edge{}
+	}
+}
+
+// removeLines removes given train lines from all edges, so no route uses them.
+func removeLines(adjMatrix adjacencyMatrix, lineCodes []string) {
+	for from := range adjMatrix {
+		for to, e := range adjMatrix[from] {
+			for _, lineCode := range lineCodes {
+				delete(e.lines, lineCode)
+			}
+			if len(e.lines) == 0 {
+				delete(adjMatrix[from], to)
+			}
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rahulbharuka/train-route-finder/types"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,7 +28,7 @@ func TestFindRoutes(t *testing.T) {
 	h := GetHandler()
 
 	t.Run("invalid-src", func(t *testing.T) {
-		routes, err := h.FindRoutes("Wonderland", "Bugis", time.Time{}, false)
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Wonderland", Destination: "Bugis", Mode: types.RMStops})
 		assert.EqualError(t, ErrInvalidRequest, err.Error())
 		assert.Nil(t, routes)
 	})
@@ -35,19 +37,19 @@ func TestFindRoutes(t *testing.T) {
 		expectedRoutes := []*Route{
 			&Route{
 				Heading: "Number of stops to destination: 7",
-				Steps:   "Take CC line from Holland Village to Botanic Gardens. Change from CC line to DT line. Take DT line from Botanic Gardens to Bugis.",
+				Steps:   "Take CC line towards Dhoby Ghaut from Holland Village to Botanic Gardens. Change from CC line to DT line. Take DT line towards Expo from Botanic Gardens to Bugis.",
 			},
 			&Route{
 				Heading: "Number of stops to destination: 8",
-				Steps:   "Take CC line from Holland Village to Botanic Gardens. Change from CC line to DT line. Take DT line from Botanic Gardens to Little India. Change from DT line to NE line. Take NE line from Little India to Dhoby Ghaut. Change from NE line to NS line. Take NS line from Dhoby Ghaut to City Hall. Change from NS line to EW line. Take EW line from City Hall to Bugis.",
+				Steps:   "Take CC line towards Dhoby Ghaut from Holland Village to Botanic Gardens. Change from CC line to DT line. Take DT line towards Expo from Botanic Gardens to Little India. Change from DT line to NE line. Take NE line towards HarbourFront from Little India to Dhoby Ghaut. Change from NE line to NS line. Take NS line towards Marina South Pier from Dhoby Ghaut to City Hall. Change from NS line to EW line. Take EW line towards Pasir Ris from City Hall to Bugis.",
 			},
 			&Route{
 				Heading: "Number of stops to destination: 9",
-				Steps:   "Take CC line from Holland Village to Caldecott. Change from CC line to TE line. Take TE line from Caldecott to Stevens. Change from TE line to DT line. Take DT line from Stevens to Bugis.",
+				Steps:   "Take CC line towards Dhoby Ghaut from Holland Village to Caldecott. Change from CC line to TE line. Take TE line towards Gardens by the Bay from Caldecott to Stevens. Change from TE line to DT line. Take DT line towards Expo from Stevens to Bugis.",
 			},
 		}
 
-		routes, err := h.FindRoutes("Holland Village", "Bugis", time.Time{}, false)
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Mode: types.RMStops})
 		assert.NoError(t, err)
 		for i, route := range routes {
 			assert.Equal(t, expectedRoutes[i].Heading, route.Heading)
@@ -56,28 +58,892 @@ func TestFindRoutes(t *testing.T) {
 	})
 
 	t.Run("realtime-routes", func(t *testing.T) {
-		journeyTime, _ := time.Parse("2006-01-02T15:04", "2019-01-31T19:00")
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T19:00")
 
 		expectedRoutes := []*Route{
 			&Route{
-				Heading: "Expected Travel time: 150",
-				Steps:   "Take EW line from Boon Lay to Buona Vista. Change from EW line to CC line. Take CC line from Buona Vista to Botanic Gardens. Change from CC line to DT line. Take DT line from Botanic Gardens to Little India.",
+				Heading: "Expected Travel time: 146",
+				Steps:   "Take EW line towards Pasir Ris from Boon Lay to Buona Vista. Change from EW line to CC line (transfer time: 15). Take CC line towards Dhoby Ghaut from Buona Vista to Botanic Gardens. Change from CC line to DT line (transfer time: 15). Take DT line towards Expo from Botanic Gardens to Little India.",
+			},
+			&Route{
+				Heading: "Expected Travel time: 167",
+				Steps:   "Take EW line towards Pasir Ris from Boon Lay to Outram Park. Change from EW line to NE line (transfer time: 15). Take NE line towards Punggol from Outram Park to Little India.",
+			},
+			&Route{
+				Heading: "Expected Travel time: 174",
+				Steps:   "Take EW line towards Pasir Ris from Boon Lay to Buona Vista. Change from EW line to CC line (transfer time: 15). Take CC line towards Dhoby Ghaut from Buona Vista to Caldecott. Change from CC line to TE line (transfer time: 15). Take TE line towards Gardens by the Bay from Caldecott to Stevens. Change from TE line to DT line (transfer time: 10). Take DT line towards Expo from Stevens to Little India.",
 			},
+		}
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Little India", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		for i, route := range routes {
+			assert.Equal(t, expectedRoutes[i].Heading, route.Heading)
+			assert.Equal(t, expectedRoutes[i].Steps, route.Steps)
+		}
+	})
+
+	t.Run("realtime-route-legs", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T19:00")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Little India", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, 146, routes[0].Cost)
+		assert.Equal(t, 12, routes[0].Stops)
+		assert.Equal(t, 2, routes[0].Interchanges)
+		assert.Equal(t, 3, len(routes[0].Legs))
+		assert.Equal(t, &RouteLeg{
+			Line:                 "DT",
+			Terminus:             "Expo",
+			BoardStation:         "Botanic Gardens",
+			BoardCode:            "DT9",
+			AlightStation:        "Little India",
+			AlightCode:           "DT12",
+			IntermediateStations: []string{"Stevens", "Newton"},
+			Stops:                3,
+			Duration:             26,
+			InterchangeCost:      15,
+			Departure:            "2022-01-31T21:00",
+			Arrival:              "2022-01-31T21:26",
+		}, routes[0].Legs[2])
+		assert.Equal(t, "2022-01-31T19:00", routes[0].Departure)
+	})
+
+	t.Run("realtime-routes-before-station-opening", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2019-01-31T19:00")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Little India", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		for _, route := range routes {
+			assert.NotContains(t, route.Steps, "TE line")
+		}
+	})
+
+	t.Run("realtime-routes-skip-unopened-infill-station", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2019-01-31T12:00")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Sembawang", Destination: "Yishun", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 10", routes[0].Heading)
+		assert.Equal(t, "Take NS line towards Marina South Pier from Sembawang to Yishun.", routes[0].Steps)
+	})
+
+	t.Run("station-not-opened", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2019-01-31T19:00")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Woodlands South", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.EqualError(t, err, "no route exist: Woodlands South station opens on 31 December 2019")
+		assert.Nil(t, routes)
+	})
+
+	t.Run("night-routes-skip-closed-lines", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T23:00")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, routes)
+		for _, route := range routes {
+			assert.NotContains(t, route.Steps, "DT line")
+		}
+	})
+
+	t.Run("night-routes-closed-line-only", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T23:00")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Bugis", Destination: "Changi Airport", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.EqualError(t, err, "no route exist: CG line is not in service during Night hours")
+		assert.Nil(t, routes)
+	})
+
+	t.Run("realtime-routes-optimal-interchanges", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+		expectedHeadings := []string{
+			"Expected Travel time: 134",
+			"Expected Travel time: 144",
+			"Expected Travel time: 146",
+		}
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Admiralty", Destination: "Bugis", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Take NS line towards Jurong East from Admiralty to Woodlands. Change from NS line to TE line (transfer time: 10). Take TE line towards Gardens by the Bay from Woodlands to Stevens. Change from TE line to DT line (transfer time: 10). Take DT line towards Expo from Stevens to Bugis.", routes[0].Steps)
+		for i, route := range routes {
+			assert.Equal(t, expectedHeadings[i], route.Heading)
+		}
+	})
+
+	t.Run("realtime-routes-parallel-train-lines", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+		// Raffles Place and City Hall are adjacent on both EW and NS lines.
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Tanjong Pagar", Destination: "Bugis", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 30", routes[0].Heading)
+		assert.Equal(t, "Take EW line towards Pasir Ris from Tanjong Pagar to Bugis.", routes[0].Steps)
+	})
+
+	t.Run("transfers-routes", func(t *testing.T) {
+		expectedHeadings := []string{
+			"Number of interchanges: 0, Number of stops to destination: 15",
+			"Number of interchanges: 2, Number of stops to destination: 14",
+		}
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Bugis", Mode: types.RMTransfers})
+		assert.NoError(t, err)
+		assert.Equal(t, "Take EW line towards Pasir Ris from Boon Lay to Bugis.", routes[0].Steps)
+		for i, heading := range expectedHeadings {
+			assert.Equal(t, heading, routes[i].Heading)
+		}
+	})
+
+	t.Run("time-mode-without-journey-time", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Bugis", Mode: types.RMTime})
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+		assert.Nil(t, routes)
+	})
+
+	t.Run("pareto-routes", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T19:00")
+
+		expectedRoutes := []*Route{
 			&Route{
-				Heading: "Expected Travel time: 173",
-				Steps:   "Take EW line from Boon Lay to Outram Park. Change from EW line to NE line. Take NE line from Outram Park to Little India.",
+				Heading: "Expected Travel time: 146, Number of stops to destination: 12, Number of interchanges: 2",
+				Steps:   "Take EW line towards Pasir Ris from Boon Lay to Buona Vista. Change from EW line to CC line (transfer time: 15). Take CC line towards Dhoby Ghaut from Buona Vista to Botanic Gardens. Change from CC line to DT line (transfer time: 15). Take DT line towards Expo from Botanic Gardens to Little India.",
 			},
 			&Route{
-				Heading: "Expected Travel time: 185",
-				Steps:   "Take EW line from Boon Lay to Buona Vista. Change from EW line to CC line. Take CC line from Buona Vista to Caldecott. Change from CC line to TE line. Take TE line from Caldecott to Stevens. Change from TE line to DT line. Take DT line from Stevens to Little India.",
+				Heading: "Expected Travel time: 167, Number of stops to destination: 15, Number of interchanges: 1",
+				Steps:   "Take EW line towards Pasir Ris from Boon Lay to Outram Park. Change from EW line to NE line (transfer time: 15). Take NE line towards Punggol from Outram Park to Little India.",
 			},
 		}
 
-		routes, err := h.FindRoutes("Boon Lay", "Little India", journeyTime, true)
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Little India", JourneyTime: journeyTime, Mode: types.RMPareto})
 		assert.NoError(t, err)
+		assert.Equal(t, len(expectedRoutes), len(routes))
 		for i, route := range routes {
 			assert.Equal(t, expectedRoutes[i].Heading, route.Heading)
 			assert.Equal(t, expectedRoutes[i].Steps, route.Steps)
 		}
 	})
+
+	t.Run("via-route", func(t *testing.T) {
+		expectedRoute := &Route{
+			Heading: "Number of stops to destination: 8",
+			Steps:   "Take CC line towards Dhoby Ghaut from Holland Village to Botanic Gardens. Change from CC line to DT line. Take DT line towards Expo from Botanic Gardens to Little India. Change from DT line to NE line. Take NE line towards HarbourFront from Little India to Dhoby Ghaut. Change from NE line to NS line. Take NS line towards Marina South Pier from Dhoby Ghaut to City Hall. Change from NS line to EW line. Take EW line towards Pasir Ris from City Hall to Bugis.",
+			Segments: []*RouteSegment{
+				&RouteSegment{
+					From:    "Holland Village",
+					To:      "Dhoby Ghaut",
+					Heading: "Number of stops to destination: 6",
+					Steps:   "Take CC line towards Dhoby Ghaut from Holland Village to Botanic Gardens. Change from CC line to DT line. Take DT line towards Expo from Botanic Gardens to Little India. Change from DT line to NE line. Take NE line towards HarbourFront from Little India to Dhoby Ghaut.",
+				},
+				&RouteSegment{
+					From:    "Dhoby Ghaut",
+					To:      "Bugis",
+					Heading: "Number of stops to destination: 2",
+					Steps:   "Take NS line towards Marina South Pier from Dhoby Ghaut to City Hall. Change from NS line to EW line. Take EW line towards Pasir Ris from City Hall to Bugis.",
+				},
+			},
+		}
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Via: []string{"Dhoby Ghaut"}, Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(routes))
+		assert.Equal(t, expectedRoute.Heading, routes[0].Heading)
+		assert.Equal(t, expectedRoute.Steps, routes[0].Steps)
+		assert.Equal(t, expectedRoute.Segments, routes[0].Segments)
+		assert.Equal(t, 8, routes[0].Cost)
+	})
+
+	t.Run("invalid-via", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Via: []string{"Wonderland"}, Mode: types.RMStops})
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+		assert.Nil(t, routes)
+	})
+
+	t.Run("avoid-stations-and-lines", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", AvoidStations: []string{"Botanic Gardens"}, AvoidLines: []string{"TE"}, Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, routes)
+		for _, route := range routes {
+			assert.NotContains(t, route.Steps, "Botanic Gardens")
+			assert.NotContains(t, route.Steps, "TE line")
+		}
+	})
+
+	t.Run("avoid-only-route", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Changi Airport", Destination: "Bugis", AvoidLines: []string{"CG"}, Mode: types.RMStops})
+		assert.EqualError(t, err, "no route exist: route depends on avoided stations or train lines")
+		assert.Nil(t, routes)
+	})
+
+	t.Run("avoid-invalid-line", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", AvoidLines: []string{"XX"}, Mode: types.RMStops})
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+		assert.Nil(t, routes)
+	})
+
+	t.Run("realtime-routes-across-hour-types", func(t *testing.T) {
+		// every DT line stop costs 10 minutes in peak hours and 8 minutes in non-peak hours.
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Botanic Gardens", Destination: "Bugis", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 50", routes[0].Heading)
+
+		journeyTime, _ = time.Parse("2006-01-02T15:04", "2022-01-31T08:50")
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Botanic Gardens", Destination: "Bugis", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 42", routes[0].Heading)
+		assert.Equal(t, "Take DT line towards Expo from Botanic Gardens to Bugis.", routes[0].Steps)
+	})
+
+	t.Run("arrive-by-routes", func(t *testing.T) {
+		arriveBy, _ := time.Parse("2006-01-02T15:04", "2022-01-31T09:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Botanic Gardens", Destination: "Bugis", ArriveBy: arriveBy, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(routes))
+		assert.Equal(t, "Latest departure time: 2022-01-31T08:10, Expected Travel time: 50", routes[0].Heading)
+		assert.Equal(t, "Take DT line towards Expo from Botanic Gardens to Bugis.", routes[0].Steps)
+
+		// departing at the latest departure time arrives by the arrival time.
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:10")
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Botanic Gardens", Destination: "Bugis", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 50", routes[0].Heading)
+	})
+
+	t.Run("arrive-by-across-peak-hours", func(t *testing.T) {
+		// journey departs during morning peak hours and arrives during non-peak hours.
+		arriveBy, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Woodlands North", Destination: "Changi Airport", ArriveBy: arriveBy, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(routes))
+		assert.Equal(t, "Latest departure time: 2022-01-31T07:52, Expected Travel time: 248", routes[0].Heading)
+		assert.Equal(t, "2022-01-31T07:52", routes[0].Departure)
+		assert.Equal(t, 248, routes[0].Cost)
+		for _, route := range routes {
+			travelTime := 0
+			for _, leg := range route.Legs {
+				travelTime = travelTime + leg.Duration + leg.WaitTime + leg.InterchangeCost
+			}
+			assert.Equal(t, route.Cost, travelTime)
+			assert.Equal(t, "2022-01-31T12:00", route.Legs[len(route.Legs)-1].Arrival)
+		}
+
+		// departing at the latest departure time arrives by the arrival time.
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T07:52")
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Woodlands North", Destination: "Changi Airport", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 248", routes[0].Heading)
+		assert.Equal(t, "2022-01-31T12:00", routes[0].Legs[len(routes[0].Legs)-1].Arrival)
+	})
+
+	t.Run("arrive-by-with-journey-time", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Botanic Gardens", Destination: "Bugis", JourneyTime: journeyTime, ArriveBy: journeyTime, Mode: types.RMTime})
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+		assert.Nil(t, routes)
+	})
+
+	t.Run("realtime-routes-into-night-hours", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T21:50")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Bugis", Destination: "Changi Airport", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.EqualError(t, err, "no route exist: CG line is not in service during Night hours")
+		assert.Nil(t, routes)
+	})
+}
+
+func TestFindRoutesWithSegmentCosts(t *testing.T) {
+	os.Setenv("SEGMENT_COST_FILE", "testdata/segment_cost.csv")
+	readSegmentCostFile()
+	initRailNetworkAdjacencyMatrix()
+	defer func() {
+		os.Unsetenv("SEGMENT_COST_FILE")
+		segmentCostMap = map[[2]string][3]int{}
+		initRailNetworkAdjacencyMatrix()
+	}()
+	h := GetHandler()
+
+	t.Run("segment-cost", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Clementi", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 4", routes[0].Heading)
+	})
+
+	t.Run("segment-cost-across-unopened-station", func(t *testing.T) {
+		// Dover opened in 2001 between Clementi and Buona Vista.
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "1995-01-31T12:00")
+		adjMatrix := createAdjacencyMatrix(journeyTime)
+		clementi, buonaVista := stationNameMap["Clementi"].idx, stationNameMap["Buona Vista"].idx
+		assert.Equal(t, 5, adjMatrix[clementi][buonaVista].lines["EW"].nonPeakHour)
+		assert.Equal(t, 6, adjMatrix[buonaVista][clementi].lines["EW"].peakHour)
+	})
+
+	t.Run("trainline-cost-fallback", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Clementi", Destination: "Buona Vista", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 20", routes[0].Heading)
+	})
+}
+
+func TestFindRoutesWithStationInterchangeCosts(t *testing.T) {
+	os.Setenv("STATION_INTERCHANGE_COST_FILE", "testdata/station_interchange_cost.csv")
+	readStationInterchangeCostFile()
+	defer func() {
+		os.Unsetenv("STATION_INTERCHANGE_COST_FILE")
+		stationInterchangeCostMap = map[interchange]map[types.HourType]int{}
+	}()
+	h := GetHandler()
+	journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+	t.Run("station-interchange-cost", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Bukit Batok", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 42", routes[0].Heading)
+		assert.Equal(t, "Take EW line towards Pasir Ris from Boon Lay to Jurong East. Change from EW line to NS line (transfer time: 2). Take NS line towards Marina South Pier from Jurong East to Bukit Batok.", routes[0].Steps)
+	})
+
+	t.Run("station-interchange-cost-arrive-by", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Boon Lay", Destination: "Bukit Batok", ArriveBy: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Latest departure time: 2022-01-31T11:18, Expected Travel time: 42", routes[0].Heading)
+		assert.Equal(t, "2022-01-31T11:18", routes[0].Departure)
+		assert.Equal(t, "2022-01-31T12:00", routes[0].Legs[len(routes[0].Legs)-1].Arrival)
+	})
+
+	t.Run("interchange-at-cheaper-shared-station", func(t *testing.T) {
+		// City Hall and Raffles Place are both on EW and NS lines; changing at Raffles Place is cheaper.
+		cityHall, rafflesPlace := stationNameMap["City Hall"].idx, stationNameMap["Raffles Place"].idx
+		stationInterchangeCostMap[interchange{stationIdx: cityHall, fromLine: "EW", toLine: "NS"}] = map[types.HourType]int{types.HTNonPeak: 50}
+		stationInterchangeCostMap[interchange{stationIdx: rafflesPlace, fromLine: "EW", toLine: "NS"}] = map[types.HourType]int{types.HTNonPeak: 1}
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Tanjong Pagar", Destination: "Dhoby Ghaut", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 31", routes[0].Heading)
+		assert.Equal(t, "Take EW line towards Pasir Ris from Tanjong Pagar to Raffles Place. Change from EW line to NS line (transfer time: 1). Take NS line towards Jurong East from Raffles Place to Dhoby Ghaut.", routes[0].Steps)
+		travelTime := 0
+		for _, leg := range routes[0].Legs {
+			travelTime = travelTime + leg.Duration + leg.WaitTime + leg.InterchangeCost
+		}
+		assert.Equal(t, routes[0].Cost, travelTime)
+	})
+
+	t.Run("interchange-cost-fallback", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Bukit Batok", Destination: "Boon Lay", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 50", routes[0].Heading)
+		assert.Equal(t, "Take NS line towards Jurong East from Bukit Batok to Jurong East. Change from NS line to EW line (transfer time: 10). Take EW line towards Tuas Link from Jurong East to Boon Lay.", routes[0].Steps)
+	})
+}
+
+func TestFindRoutesWithWalkingLinks(t *testing.T) {
+	os.Setenv("WALKING_LINKS_FILE", "testdata/walking_links.csv")
+	readWalkingLinksFile()
+	initRailNetworkAdjacencyMatrix()
+	defer func() {
+		os.Unsetenv("WALKING_LINKS_FILE")
+		walkingLinkMap = map[[2]int]int{}
+		initRailNetworkAdjacencyMatrix()
+	}()
+	h := GetHandler()
+	journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+	t.Run("walking-route", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Esplanade", Destination: "Raffles Place", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 16", routes[0].Heading)
+		assert.Equal(t, "Walk from Esplanade to City Hall (6 min). Take EW line towards Tuas Link from City Hall to Raffles Place.", routes[0].Steps)
+	})
+
+	t.Run("walking-before-first-train", func(t *testing.T) {
+		// walking before the first train (or after the last one) is not an interchange.
+		for _, q := range [][2]string{{"Esplanade", "Raffles Place"}, {"Raffles Place", "Esplanade"}} {
+			routes, _, err := h.FindRoutes(&RouteQuery{Source: q[0], Destination: q[1], JourneyTime: journeyTime, Mode: types.RMTransfers})
+			assert.NoError(t, err)
+			assert.Equal(t, "Number of interchanges: 0, Expected Travel time: 16", routes[0].Heading)
+			assert.Equal(t, 0, routes[0].Interchanges)
+		}
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Esplanade", Destination: "Raffles Place", JourneyTime: journeyTime, Mode: types.RMPareto})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 16, Number of stops to destination: 2, Number of interchanges: 0", routes[0].Heading)
+
+		matrix, err := h.FindMatrix(&MatrixQuery{Sources: []string{"Esplanade", "Raffles Place"}, Destinations: []string{"Raffles Place", "Esplanade"}, JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, &MatrixCell{Cost: 16, Stops: 2, Interchanges: 0}, matrix.Cells[0][0])
+		assert.Equal(t, &MatrixCell{Cost: 16, Stops: 2, Interchanges: 0}, matrix.Cells[1][1])
+	})
+
+	t.Run("walking-route-between-train-lines", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jalan Besar", Destination: "Esplanade", JourneyTime: journeyTime, Mode: types.RMTransfers})
+		assert.NoError(t, err)
+		// walking between two train lines counts as a single interchange.
+		assert.Equal(t, "Number of interchanges: 1, Expected Travel time: 23", routes[0].Heading)
+		assert.Equal(t, "Take DT line towards Bukit Panjang from Jalan Besar to Bencoolen. Walk from Bencoolen to Bras Basah (5 min). Take CC line towards HarbourFront from Bras Basah to Esplanade.", routes[0].Steps)
+	})
+
+	t.Run("walking-link-before-station-opening", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2010-01-31T12:00")
+		adjMatrix := createAdjacencyMatrix(journeyTime)
+		_, ok := adjMatrix[stationNameMap["Bras Basah"].idx][stationNameMap["Bencoolen"].idx]
+		assert.False(t, ok)
+	})
+}
+
+func TestDisruptions(t *testing.T) {
+	h := GetHandler()
+	journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+	t.Run("line-disruption", func(t *testing.T) {
+		d, err := h.AddDisruption(&Disruption{Line: "DT", Reason: "signal fault"})
+		assert.NoError(t, err)
+		assert.Equal(t, []*Disruption{d}, h.ListDisruptions())
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Mode: types.RMStops})
+		assert.NoError(t, err)
+		for _, route := range routes {
+			assert.NotContains(t, route.Steps, "DT line")
+		}
+
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Botanic Gardens", Destination: "Bugis", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		for _, route := range routes {
+			assert.NotContains(t, route.Steps, "DT line")
+		}
+
+		assert.NoError(t, h.RemoveDisruption(d.ID))
+		assert.Empty(t, h.ListDisruptions())
+
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Botanic Gardens", Destination: "Bugis", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Take DT line towards Expo from Botanic Gardens to Bugis.", routes[0].Steps)
+	})
+
+	t.Run("segment-disruption-only-route", func(t *testing.T) {
+		d, err := h.AddDisruption(&Disruption{From: "Tuas Link", To: "Tuas West Road"})
+		assert.NoError(t, err)
+		defer h.RemoveDisruption(d.ID)
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Tuas West Road", Destination: "Tuas Link", Mode: types.RMStops})
+		assert.EqualError(t, err, "no route exist: route depends on disrupted stations or train lines")
+		assert.Nil(t, routes)
+	})
+
+	t.Run("segment-disruption-on-shared-line", func(t *testing.T) {
+		// Raffles Place and City Hall are adjacent on both EW and NS lines.
+		for _, lineCode := range []string{"", "EW", "NS"} {
+			d, err := h.AddDisruption(&Disruption{From: "City Hall", To: "Raffles Place", Line: lineCode})
+			assert.NoError(t, err)
+			assert.NoError(t, h.RemoveDisruption(d.ID))
+		}
+	})
+
+	t.Run("invalid-disruption", func(t *testing.T) {
+		for _, d := range []*Disruption{
+			&Disruption{},
+			&Disruption{Station: "Bugis", Line: "EW"},
+			&Disruption{Line: "XX"},
+			&Disruption{From: "Bugis", To: "Jurong East"},
+			&Disruption{From: "Bugis", To: "Lavender", Line: "DT"},
+		} {
+			_, err := h.AddDisruption(d)
+			assert.EqualError(t, ErrInvalidRequest, err.Error())
+		}
+		assert.Empty(t, h.ListDisruptions())
+	})
+
+	t.Run("remove-unknown-disruption", func(t *testing.T) {
+		assert.EqualError(t, h.RemoveDisruption("unknown"), ErrDisruptionNotFound.Error())
+	})
+}
+
+func TestScheduledClosures(t *testing.T) {
+	os.Setenv("CLOSURES_FILE", "testdata/closures.csv")
+	readClosuresFile()
+	h := GetHandler()
+	defer func() {
+		os.Unsetenv("CLOSURES_FILE")
+		for _, d := range h.ListDisruptions() {
+			h.RemoveDisruption(d.ID)
+		}
+	}()
+
+	t.Run("closure-active", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-02-06T08:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Promenade", Destination: "Marina Bay", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Take DT line towards Expo from Promenade to Bayfront. Change from DT line to CE line (transfer time: 10). Take CE line towards Marina Bay from Bayfront to Marina Bay.", routes[0].Steps)
+		assert.Equal(t, 1, len(routes[0].Closures))
+		assert.Equal(t, "Sunday maintenance", routes[0].Closures[0].Reason)
+	})
+
+	t.Run("closure-not-active", func(t *testing.T) {
+		for _, jTime := range []string{"2022-02-06T10:00", "2022-02-07T08:00", "2022-01-23T08:00"} {
+			journeyTime, _ := time.Parse("2006-01-02T15:04", jTime)
+			routes, _, err := h.FindRoutes(&RouteQuery{Source: "Promenade", Destination: "Marina Bay", JourneyTime: journeyTime, Mode: types.RMTime})
+			assert.NoError(t, err)
+			assert.Equal(t, "Take CE line towards Marina Bay from Promenade to Marina Bay.", routes[0].Steps)
+			assert.Nil(t, routes[0].Closures)
+		}
+	})
+
+	t.Run("closure-without-journey-time", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Promenade", Destination: "Marina Bay", Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.Equal(t, "Take CE line towards Marina Bay from Promenade to Marina Bay.", routes[0].Steps)
+	})
+
+	t.Run("invalid-closure-window", func(t *testing.T) {
+		_, err := h.AddDisruption(&Disruption{Line: "CE", Start: "2022-01-30T10:00", End: "2022-01-30T06:00"})
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+	})
+}
+
+func TestFindRoutesWithHeadways(t *testing.T) {
+	os.Setenv("HEADWAY_FILE", "testdata/headway.csv")
+	readHeadwayFile()
+	defer func() {
+		os.Unsetenv("HEADWAY_FILE")
+		lineHeadwayMap = map[string]map[types.HourType]int{}
+	}()
+	h := GetHandler()
+	journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+	t.Run("waiting-time-at-first-boarding", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Clementi", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 13, Expected waiting time: 3", routes[0].Heading)
+	})
+
+	t.Run("waiting-time-at-interchange", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Clementi", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 47, Expected waiting time: 7", routes[0].Heading)
+		assert.Equal(t, "Take CC line towards HarbourFront from Holland Village to Buona Vista. Change from CC line to EW line (transfer time: 10). Take EW line towards Tuas Link from Buona Vista to Clementi.", routes[0].Steps)
+	})
+
+	t.Run("waiting-time-arrive-by", func(t *testing.T) {
+		arriveBy, _ := time.Parse("2006-01-02T15:04", "2022-01-31T13:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Clementi", ArriveBy: arriveBy, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Latest departure time: 2022-01-31T12:13, Expected Travel time: 47, Expected waiting time: 7", routes[0].Heading)
+	})
+
+	t.Run("no-waiting-time-in-stops-mode", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Clementi", Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.Equal(t, "Number of stops to destination: 1", routes[0].Heading)
+	})
+}
+
+func TestFindReachable(t *testing.T) {
+	h := GetHandler()
+	journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+	t.Run("invalid-budget", func(t *testing.T) {
+		stations, err := h.FindReachable(&ReachableQuery{Source: "Jurong East", JourneyTime: journeyTime})
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+		assert.Nil(t, stations)
+	})
+
+	t.Run("reachable-stations", func(t *testing.T) {
+		stations, err := h.FindReachable(&ReachableQuery{Source: "Jurong East", JourneyTime: journeyTime, MaxMinutes: 20})
+		assert.NoError(t, err)
+		assert.Equal(t, []*ReachableStation{
+			&ReachableStation{Station: "Bukit Batok", Cost: 10, Line: "NS"},
+			&ReachableStation{Station: "Chinese Garden", Cost: 10, Line: "EW"},
+			&ReachableStation{Station: "Clementi", Cost: 10, Line: "EW"},
+			&ReachableStation{Station: "Bukit Gombak", Cost: 20, Line: "NS"},
+			&ReachableStation{Station: "Dover", Cost: 20, Line: "EW"},
+			&ReachableStation{Station: "Lakeside", Cost: 20, Line: "EW"},
+		}, stations)
+	})
+
+	t.Run("unopened-src", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "1990-01-01T12:00")
+		stations, err := h.FindReachable(&ReachableQuery{Source: "Woodlands South", JourneyTime: journeyTime, MaxMinutes: 20})
+		assert.EqualError(t, err, "no route exist: Woodlands South station opens on 31 December 2019")
+		assert.Nil(t, stations)
+	})
+}
+
+func TestFindMatrix(t *testing.T) {
+	h := GetHandler()
+
+	t.Run("invalid-dst", func(t *testing.T) {
+		matrix, err := h.FindMatrix(&MatrixQuery{Sources: []string{"Boon Lay"}, Destinations: []string{"Wonderland"}, Mode: types.RMStops})
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+		assert.Nil(t, matrix)
+	})
+
+	t.Run("stops-matrix", func(t *testing.T) {
+		matrix, err := h.FindMatrix(&MatrixQuery{Sources: []string{"Holland Village", "Boon Lay"}, Destinations: []string{"Bugis", "Boon Lay"}, Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]*MatrixCell{
+			{&MatrixCell{Cost: 7, Stops: 7, Interchanges: 1}, &MatrixCell{Cost: 7, Stops: 7, Interchanges: 1}},
+			{&MatrixCell{Cost: 14, Stops: 14, Interchanges: 2}, &MatrixCell{Cost: 0, Stops: 0, Interchanges: 0}},
+		}, matrix.Cells)
+	})
+
+	t.Run("time-matrix", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T19:00")
+		matrix, err := h.FindMatrix(&MatrixQuery{Sources: []string{"Boon Lay"}, Destinations: []string{"Bugis", "Little India"}, JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]*MatrixCell{
+			{&MatrixCell{Cost: 150, Stops: 15, Interchanges: 0}, &MatrixCell{Cost: 146, Stops: 12, Interchanges: 2}},
+		}, matrix.Cells)
+	})
+
+	t.Run("unopened-dst", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2019-01-01T12:00")
+		matrix, err := h.FindMatrix(&MatrixQuery{Sources: []string{"Woodlands"}, Destinations: []string{"Woodlands South"}, JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]*MatrixCell{{nil}}, matrix.Cells)
+	})
+}
+
+func TestFindRoutesWithAlternativesFilter(t *testing.T) {
+	defer func() {
+		os.Unsetenv("ALTERNATIVES_MAX_EXTRA_COST")
+		os.Unsetenv("ALTERNATIVES_MAX_SHARED_EDGES")
+		setAlternativesFilter()
+	}()
+	h := GetHandler()
+
+	t.Run("max-shared-edges", func(t *testing.T) {
+		os.Setenv("ALTERNATIVES_MAX_SHARED_EDGES", "50")
+		setAlternativesFilter()
+
+		// near duplicates of the 7 stops route through Little India and Stevens are dropped.
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(routes))
+		assert.Equal(t, "Number of stops to destination: 7", routes[0].Heading)
+		assert.Equal(t, "Number of stops to destination: 9", routes[1].Heading)
+		assert.Equal(t, "Take CC line towards Dhoby Ghaut from Holland Village to Botanic Gardens. Change from CC line to DT line. Take DT line towards Expo from Botanic Gardens to Newton. Change from DT line to NS line. Take NS line towards Marina South Pier from Newton to City Hall. Change from NS line to EW line. Take EW line towards Pasir Ris from City Hall to Bugis.", routes[1].Steps)
+		assert.Equal(t, "Number of stops to destination: 10", routes[2].Heading)
+		assert.Equal(t, "Take CC line towards HarbourFront from Holland Village to Buona Vista. Change from CC line to EW line. Take EW line towards Pasir Ris from Buona Vista to Bugis.", routes[2].Steps)
+	})
+
+	t.Run("max-extra-cost", func(t *testing.T) {
+		os.Unsetenv("ALTERNATIVES_MAX_SHARED_EDGES")
+		os.Setenv("ALTERNATIVES_MAX_EXTRA_COST", "10")
+		setAlternativesFilter()
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(routes))
+		assert.Equal(t, "Number of stops to destination: 7", routes[0].Heading)
+	})
+
+	t.Run("zero-max-extra-cost", func(t *testing.T) {
+		os.Setenv("ALTERNATIVES_MAX_EXTRA_COST", "0")
+		setAlternativesFilter()
+		assert.Equal(t, 0, alternatives.maxExtraCost)
+
+		// alternatives must cost no more than the best route.
+		routes, fewer, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(routes))
+		assert.Equal(t, "Number of stops to destination: 7", routes[0].Heading)
+		assert.True(t, fewer)
+
+		os.Unsetenv("ALTERNATIVES_MAX_EXTRA_COST")
+		setAlternativesFilter()
+		assert.False(t, alternatives.isEnabled())
+	})
+
+	t.Run("max-extra-cost-in-transfers-mode", func(t *testing.T) {
+		os.Setenv("ALTERNATIVES_MAX_EXTRA_COST", "50")
+		setAlternativesFilter()
+
+		// a route with more interchanges is kept as its stop count is within max extra cost.
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Mode: types.RMTransfers})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(routes))
+		assert.Equal(t, "Number of interchanges: 1, Number of stops to destination: 7", routes[0].Heading)
+		assert.Equal(t, "Number of interchanges: 1, Number of stops to destination: 10", routes[1].Heading)
+		assert.Equal(t, "Number of interchanges: 3, Number of stops to destination: 9", routes[2].Heading)
+	})
+}
+
+func TestFindRoutesWithRouteCount(t *testing.T) {
+	h := GetHandler()
+
+	t.Run("fewer-routes-than-max", func(t *testing.T) {
+		routes, fewer, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Mode: types.RMStops, K: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(routes))
+		assert.Equal(t, "Number of stops to destination: 7", routes[0].Heading)
+		assert.False(t, fewer)
+	})
+
+	t.Run("capped-by-max-routes", func(t *testing.T) {
+		routes, fewer, err := h.FindRoutes(&RouteQuery{Source: "Holland Village", Destination: "Bugis", Mode: types.RMStops, K: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, h.MaxRoutes(), len(routes))
+		assert.False(t, fewer)
+	})
+
+	t.Run("fewer-routes-exist", func(t *testing.T) {
+		// only East West line is opened.
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "1989-01-01T12:00")
+		routes, fewer, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Clementi", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.True(t, fewer)
+		assert.Equal(t, []*Route{
+			&Route{
+				Source:      "Jurong East",
+				Destination: "Clementi",
+				Departure:   "1989-01-01T12:00",
+				Heading:     "Expected Travel time: 10",
+				Steps:       "Take EW line towards City Hall from Jurong East to Clementi.",
+				Cost:        10,
+				Stops:       1,
+				Legs: []*RouteLeg{
+					&RouteLeg{
+						Line:                 "EW",
+						Terminus:             "City Hall",
+						BoardStation:         "Jurong East",
+						BoardCode:            "EW24",
+						AlightStation:        "Clementi",
+						AlightCode:           "EW23",
+						IntermediateStations: []string{},
+						Stops:                1,
+						Duration:             10,
+						Departure:            "1989-01-01T12:00",
+						Arrival:              "1989-01-01T12:10",
+					},
+				},
+			},
+		}, routes)
+	})
+}
+
+func TestFindRoutesWithLanguages(t *testing.T) {
+	os.Setenv("MESSAGES_FILE", "testdata/messages.csv")
+	os.Setenv("STATION_NAMES_FILE", "testdata/station_names.csv")
+	readMessagesFile()
+	readStationNamesFile()
+	defer func() {
+		os.Unsetenv("MESSAGES_FILE")
+		os.Unsetenv("STATION_NAMES_FILE")
+		delete(messageCatalog, "id")
+		localizedStationNameMap = map[string]map[string]string{}
+	}()
+	h := GetHandler()
+	journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+	t.Run("localized-route", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime, Languages: []string{"zh-SG"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "预计行程时间：50", routes[0].Heading)
+		assert.Equal(t, "乘坐EW线（往巴西立方向），从裕廊东到波那维斯达。从EW线换乘CC线（换乘时间：10）。乘坐CC线（往多美歌方向），从波那维斯达到荷兰村。", routes[0].Steps)
+		assert.Equal(t, "Jurong East", routes[0].Legs[0].BoardStation)
+	})
+
+	t.Run("first-supported-language", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", Mode: types.RMTransfers, Languages: []string{"fr", "ms"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "Bilangan pertukaran: 1, Bilangan hentian ke destinasi: 4", routes[0].Heading)
+		assert.Equal(t, "Naik laluan EW arah Pasir Ris dari Jurong East ke Buona Vista. Tukar dari laluan EW ke laluan CC. Naik laluan CC arah Dhoby Ghaut dari Buona Vista ke Holland Village.", routes[0].Steps)
+	})
+
+	t.Run("unsupported-language", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", Mode: types.RMStops, Languages: []string{"fr"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "Number of stops to destination: 4", routes[0].Heading)
+	})
+
+	t.Run("language-from-messages-file", func(t *testing.T) {
+		// templates missing from messages file fall back to default language.
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime, Languages: []string{"id"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "Perkiraan waktu perjalanan: 50", routes[0].Heading)
+		assert.Equal(t, "Naik jalur EW arah Pasir Ris dari Jurong East ke Buona Vista. Change from EW line to CC line (transfer time: 10). Naik jalur CC arah Dhoby Ghaut dari Buona Vista ke Holland Village.", routes[0].Steps)
+	})
+}
+
+func TestSearchStations(t *testing.T) {
+	os.Setenv("STATION_ALIASES_FILE", "testdata/station_aliases.csv")
+	readStationAliasesFile()
+	defer func() {
+		os.Unsetenv("STATION_ALIASES_FILE")
+		stationAliasMap = map[string]string{}
+	}()
+	h := GetHandler()
+
+	t.Run("empty-query", func(t *testing.T) {
+		matches, err := h.SearchStations(" ")
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+		assert.Nil(t, matches)
+	})
+
+	t.Run("case-insensitive-exact", func(t *testing.T) {
+		matches, err := h.SearchStations("Harbourfront")
+		assert.NoError(t, err)
+		assert.Equal(t, []*StationMatch{
+			&StationMatch{Station: "HarbourFront", Codes: []string{"CC29", "NE1"}, Match: "exact"},
+		}, matches)
+	})
+
+	t.Run("prefix-token-alias-and-fuzzy", func(t *testing.T) {
+		matches, err := h.SearchStations("hol")
+		assert.NoError(t, err)
+		assert.Equal(t, "prefix", matches[0].Match)
+		assert.Equal(t, "Holland Village", matches[0].Station)
+
+		matches, err = h.SearchStations("village")
+		assert.NoError(t, err)
+		assert.Equal(t, "token", matches[0].Match)
+		assert.Equal(t, "Holland Village", matches[0].Station)
+
+		matches, err = h.SearchStations("marina bay sands")
+		assert.NoError(t, err)
+		assert.Equal(t, "alias", matches[0].Match)
+		assert.Equal(t, "Bayfront", matches[0].Station)
+
+		matches, err = h.SearchStations("Bugsi")
+		assert.NoError(t, err)
+		assert.Equal(t, "fuzzy", matches[0].Match)
+		assert.Equal(t, "Bugis", matches[0].Station)
+	})
+
+	t.Run("did-you-mean", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Harbourfront", Destination: "Bugis", Mode: types.RMStops})
+		assert.EqualError(t, err, "invalid request: unknown station Harbourfront, did you mean HarbourFront?")
+		assert.Equal(t, []string{"HarbourFront"}, err.(*StationNotFoundError).Suggestions)
+		assert.Nil(t, routes)
+	})
+}
+
+func TestFindRoutesWithStationCodes(t *testing.T) {
+	h := GetHandler()
+	journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T12:00")
+
+	t.Run("codes-resolve-to-station", func(t *testing.T) {
+		byName, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		for _, src := range []string{"EW24", "NS1", "ns1"} {
+			routes, _, err := h.FindRoutes(&RouteQuery{Source: src, Destination: "CC21", JourneyTime: journeyTime, Mode: types.RMTime})
+			assert.NoError(t, err)
+			assert.Equal(t, byName, routes)
+			assert.Equal(t, "Jurong East", routes[0].Source)
+			assert.Equal(t, "Holland Village", routes[0].Destination)
+		}
+	})
+
+	t.Run("codes-of-same-station", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "EW24", Destination: "NS1", Mode: types.RMStops})
+		assert.EqualError(t, ErrInvalidRequest, err.Error())
+		assert.Nil(t, routes)
+	})
+
+	t.Run("unknown-code", func(t *testing.T) {
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "EW99", Destination: "Bugis", Mode: types.RMStops})
+		assert.Error(t, err)
+		assert.Nil(t, routes)
+	})
 }
@@ -4,10 +4,10 @@ import (
 	"encoding/csv"
 	"io"
 	"log"
-	"math"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rahulbharuka/train-route-finder/types"
@@ -15,37 +15,61 @@ import (
 
 var (
 	// auxillary data structures with package wide usage.
-	stationNameMap             = map[string]*station{}    // maps a station-name to station.
-	stationIndexMap            = map[int]*station{}       // maps station index to station.
-	interchangeCostMap         = map[types.HourType]int{} // map of hourtype to interchange cost
-	railNetworkAdjacencyMatrix = adjacencyMatrix{}        // graph of whole train network.
-	topK                       int                        // max number of shortest routes to return
+	stationNameMap             = map[string]*station{}                    // maps a station-name to station.
+	stationIndexMap            = map[int]*station{}                       // maps station index to station.
+	interchangeCostMap         = map[types.HourType]int{}                 // map of hourtype to interchange cost
+	stationInterchangeCostMap  = map[interchange]map[types.HourType]int{} // map of interchange at a station to its cost per hourtype. optional
+	walkingLinkMap             = map[[2]int]int{}                         // map of station index pair to walking time between them. optional
+	lineHeadwayMap             = map[string]map[types.HourType]int{}      // map of train line to its headway per hourtype. optional
+	stationAliasMap            = map[string]string{}                      // map of lower case station alias to station name. optional
+	railNetworkAdjacencyMatrix = adjacencyMatrix{}                        // graph of whole train network.
+	topK                       int                                        // max number of shortest routes to return
+	alternatives               = newAlternativesFilter()                  // filter of reasonable alternative routes. optional
 
 	// temporary data structures for initialization
 	lineStationMap = map[string]*lineStation{} // maps stationCode to line-station.
 	lineCostMap    = map[string][3]int{}       // map of train line to travel time cost per station
+	segmentCostMap = map[[2]string][3]int{}    // map of line-station code pair to travel time cost between them. optional
+	trainLineMap   = map[string][]string{}     // maps train line to its line-station codes ordered by station number.
 )
 
+const (
+	// lineNotInService is the travel time cost in trainline-cost file for hours when train line is not in service.
+	lineNotInService = -1
+	// walkLine is the pseudo train line of walking links between stations.
+	walkLine = "WALK"
+)
+
+// lineCostHourTypes is the hour type of every cost in lineCostMap entry.
+var lineCostHourTypes = [...]types.HourType{types.HTNonPeak, types.HTPeak, types.HTNight}
+
 type adjacencyMatrix map[int]map[int]*edge
 
+// interchange is a change from one train line to another at a station.
+type interchange struct {
+	stationIdx int
+	fromLine   string
+	toLine     string
+}
+
 // edge object stores attributes of an edge
 type edge struct {
-	disabled bool    // whether its enabled or disabled. default: False
-	weight   *weight // weights for the edge
+	disabled bool               // whether its enabled or disabled. default: False
+	lines    map[string]*weight // train lines connecting the two stations mapped to their weights
 }
 
 type weight struct {
-	nonPeakHour int // non-peak hours cost
-	peakHour    int // peak hours cost
-	nightHour   int // night hours cost
-	defaults    int // default cost. Its always 1.
+	nonPeakHour int              // non-peak hours cost
+	peakHour    int              // peak hours cost
+	nightHour   int              // night hours cost
+	defaults    int              // default cost. Its always 1.
+	closedHours []types.HourType // hour types when train line is not in service
 }
 
 // lineStation is an object for a station on a specific line.
 type lineStation struct {
 	name           string
 	openingDate    time.Time
-	neighbours     map[int]*edge
 	lineStationIdx int
 }
 
@@ -68,34 +92,67 @@ func RailNetworkInit() {
 	// read interchange cost file
 	readInterchangeCostFile()
 
+	// read segment cost file, if configured
+	readSegmentCostFile()
+
+	// read station interchange cost file, if configured
+	readStationInterchangeCostFile()
+
+	// read walking links file, if configured
+	readWalkingLinksFile()
+
+	// read headway file, if configured
+	readHeadwayFile()
+
+	// read message templates file, if configured
+	readMessagesFile()
+
+	// read localized station names file, if configured
+	readStationNamesFile()
+
+	// read station aliases file, if configured
+	readStationAliasesFile()
+
 	// populate neighbours for every line-station.
 	populateNeighbours(trainLines)
 
 	// create adjacency matrix
 	initRailNetworkAdjacencyMatrix()
 
+	// read scheduled closures file, if configured
+	readClosuresFile()
+
 	// set topK value
 	setTopKValue()
+
+	// set alternatives filter, if configured
+	setAlternativesFilter()
+
+	// read timetable, if configured
+	readTimetableDir()
 }
 
-// readStationMapFile reads station-map file and initializes the multiple auxillary data structures.
-func readStationMapFile() map[string][]string {
-	csvFile := os.Getenv("STATION_MAP_FILE")
+// readCSVFile reads csv file configured by given environment variable, and calls readRecord with every record
+// after the header line along with its line number. If environment variable is not set, it fails when the file
+// is required, and does nothing otherwise.
+func readCSVFile(envVar string, required bool, readRecord func(record []string, line int)) {
+	csvFile := os.Getenv(envVar)
 	if csvFile == "" {
-		log.Fatal("$STATION_MAP_FILE must be set")
+		if required {
+			log.Fatalf("$%v must be set", envVar)
+		}
+		return
 	}
 
 	file, err := os.Open(csvFile)
 	if err != nil {
-		panic("error while opening file")
+		panic("error while opening $" + envVar + " file")
 	}
+	defer file.Close()
 
 	r := csv.NewReader(file)
 
-	trainLines := map[string][]string{} // temporary map of line to stations. Its used to order stations on a line.
-
 	line := -1
-	stationIdx := 0 // auto generated index for every station.
 	// Iterate through the records
 	for {
 		line++
@@ -108,28 +165,35 @@ func readStationMapFile() map[string][]string {
 			log.Fatal(err)
 		}
 		if line == 0 {
-			// skip the header line
-			continue
+			continue // skip the header line
 		}
+		readRecord(record, line)
+	}
+}
 
+// readStationMapFile reads station-map file and initializes the multiple auxillary data structures.
+func readStationMapFile() map[string][]string {
+	trainLines := map[string][]string{} // temporary map of line to stations. Its used to order stations on a line.
+
+	stationIdx := 0 // auto generated index for every station.
+	readCSVFile("STATION_MAP_FILE", true, func(record []string, _ int) {
 		stationCode := record[0]
 		stationName := record[1]
-		openingTime, err := time.Parse("2 January 2006", record[2])
+		openingTime, err := time.Parse(dateFormat, record[2])
 		if err != nil {
 			log.Printf("wrong date format for station %v. Skipping it\n", stationName)
-			continue
+			return
 		}
 
 		if _, ok := lineStationMap[stationCode]; ok {
 			log.Println("duplicate station entry for stationCode ", stationCode)
-			continue
+			return
 		}
 
 		// create line-station for the record.
 		lineStationMap[stationCode] = &lineStation{
 			name:        stationName,
 			openingDate: openingTime,
-			neighbours:  map[int]*edge{},
 		}
 
 		// if its an existing station, just append station code. Otherwise, create a new station object.
@@ -152,156 +216,410 @@ func readStationMapFile() map[string][]string {
 		} else {
 			trainLines[lineCode] = []string{stationCode}
 		}
-	}
+	})
 	return trainLines
 }
 
 // readTrainlineCostFile reads trainline-cost file and initializes lineCostMap.
 func readTrainlineCostFile() {
-	csvFile := os.Getenv("TRAINLINE_COST_FILE")
-	if csvFile == "" {
-		log.Fatal("$TRAINLINE_COST_FILE must be set")
-	}
-
-	file, err := os.Open(csvFile)
-	if err != nil {
-		panic("error while opening $TRAINLINE_COST_FILE file")
-	}
-
-	r := csv.NewReader(file)
-
-	line := -1
-	// Iterate through the records
-	for {
-		line++
-		// Read each record from csv
-		record, err := r.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
-		if line == 0 {
-			continue // skip the header line
-		}
-
+	readCSVFile("TRAINLINE_COST_FILE", true, func(record []string, _ int) {
 		nonPeakHoursCost, err := strconv.Atoi(record[1])
-		if err != nil {
+		if err != nil || nonPeakHoursCost < lineNotInService {
 			panic("invalid non-peak hours travel time cost")
 		}
-		if nonPeakHoursCost == -1 {
-			nonPeakHoursCost = math.MaxInt32
-		}
 
 		peakHoursCost, err := strconv.Atoi(record[2])
-		if err != nil {
+		if err != nil || peakHoursCost < lineNotInService {
 			panic("invalid peak hours travel time cost")
 		}
-		if peakHoursCost == -1 {
-			peakHoursCost = math.MaxInt32
-		}
 
 		nightHoursCost, err := strconv.Atoi(record[3])
-		if err != nil {
+		if err != nil || nightHoursCost < lineNotInService {
 			panic("invalid night hours travel time cost")
 		}
-		if nightHoursCost == -1 {
-			nightHoursCost = math.MaxInt32
-		}
 		lineCostMap[record[0]] = [...]int{nonPeakHoursCost, peakHoursCost, nightHoursCost}
-	}
+	})
 }
 
 // readInterchangeCostFile reads interchange-cost file and initializes interchangeCostMap.
 func readInterchangeCostFile() {
-	csvFile := os.Getenv("INTERCHANGE_COST_FILE")
-	if csvFile == "" {
-		log.Fatal("$INTERCHANGE_COST_FILE must be set")
-	}
+	readCSVFile("INTERCHANGE_COST_FILE", true, func(record []string, _ int) {
+		cost, err := strconv.Atoi(record[1])
+		if err != nil || cost < 0 {
+			panic("invalid interchange time cost")
+		}
+		interchangeCostMap[types.ConvertToHourType(record[0])] = cost
+	})
+}
 
-	file, err := os.Open(csvFile)
-	if err != nil {
-		panic("error while opening $INTERCHANGE_COST_FILE file")
-	}
+// readSegmentCostFile reads segment-cost file and initializes segmentCostMap.
+// If environment variable is not set, every segment costs its train line cost.
+func readSegmentCostFile() {
+	readCSVFile("SEGMENT_COST_FILE", false, func(record []string, _ int) {
+		from, to := record[0], record[1]
+		_, ok1 := lineStationMap[from]
+		_, ok2 := lineStationMap[to]
+		if !ok1 || !ok2 || from[:2] != to[:2] || from == to {
+			log.Printf("invalid segment from %v to %v\n", from, to)
+			panic("invalid segment")
+		}
 
-	r := csv.NewReader(file)
+		var costs [3]int
+		for i := range costs {
+			cost, err := strconv.Atoi(record[i+2])
+			if err != nil || cost < 0 {
+				panic("invalid segment travel time cost")
+			}
+			costs[i] = cost
+		}
+		segmentCostMap[[2]string{from, to}] = costs
+		segmentCostMap[[2]string{to, from}] = costs
+	})
+}
 
-	line := -1
-	// Iterate through the records
-	for {
-		line++
-		// Read each record from csv
-		record, err := r.Read()
-		if err == io.EOF {
-			break
+// readStationInterchangeCostFile reads station-interchange-cost file and initializes stationInterchangeCostMap.
+// If environment variable is not set, every interchange costs the interchange cost of its hour type.
+func readStationInterchangeCostFile() {
+	readCSVFile("STATION_INTERCHANGE_COST_FILE", false, func(record []string, _ int) {
+		s, ok := stationNameMap[record[0]]
+		fromLine, toLine := record[1], record[2]
+		if !ok || !s.isOnLine(fromLine) || !s.isOnLine(toLine) || fromLine == toLine {
+			log.Printf("invalid interchange from %v line to %v line at %v\n", fromLine, toLine, record[0])
+			panic("invalid interchange")
 		}
-		if err != nil {
-			log.Fatal(err)
+
+		costs := map[types.HourType]int{}
+		for i, ht := range lineCostHourTypes {
+			cost, err := strconv.Atoi(record[i+3])
+			if err != nil || cost < 0 {
+				panic("invalid station interchange time cost")
+			}
+			costs[ht] = cost
 		}
-		if line == 0 {
-			continue // skip the header line
+		stationInterchangeCostMap[interchange{stationIdx: s.idx, fromLine: fromLine, toLine: toLine}] = costs
+	})
+}
+
+// readWalkingLinksFile reads walking-links file and initializes walkingLinkMap.
+// If environment variable is not set, stations are connected by train lines only.
+func readWalkingLinksFile() {
+	readCSVFile("WALKING_LINKS_FILE", false, func(record []string, _ int) {
+		from, ok1 := stationNameMap[record[0]]
+		to, ok2 := stationNameMap[record[1]]
+		if !ok1 || !ok2 || from == to {
+			log.Printf("invalid walking link from %v to %v\n", record[0], record[1])
+			panic("invalid walking link")
 		}
 
-		cost, err := strconv.Atoi(record[1])
-		if err != nil || cost < 0 {
-			panic("invalid interchange time cost")
+		walkingTime, err := strconv.Atoi(record[2])
+		if err != nil || walkingTime <= 0 {
+			panic("invalid walking time")
 		}
-		interchangeCostMap[types.ConvertToHourType(record[0])] = cost
-	}
+		walkingLinkMap[[2]int{from.idx, to.idx}] = walkingTime
+		walkingLinkMap[[2]int{to.idx, from.idx}] = walkingTime
+	})
+}
+
+// readHeadwayFile reads headway file and initializes lineHeadwayMap.
+// If environment variable is not set, routes have no waiting time for trains.
+func readHeadwayFile() {
+	readCSVFile("HEADWAY_FILE", false, func(record []string, _ int) {
+		if _, ok := lineCostMap[record[0]]; !ok {
+			log.Printf("Trainline %v cost is not available\n", record[0])
+			panic("invalid headway train line")
+		}
+
+		headways := map[types.HourType]int{}
+		for i, ht := range lineCostHourTypes {
+			headway, err := strconv.Atoi(record[i+1])
+			if err != nil || headway < 0 {
+				panic("invalid headway")
+			}
+			headways[ht] = headway
+		}
+		lineHeadwayMap[record[0]] = headways
+	})
+}
+
+// readClosuresFile reads closures file and adds every scheduled closure in it.
+// If environment variable is not set, there is no scheduled closure until added by admin API.
+func readClosuresFile() {
+	readCSVFile("CLOSURES_FILE", false, func(record []string, line int) {
+		weekly := false
+		if record[6] != "" {
+			var err error
+			weekly, err = strconv.ParseBool(record[6])
+			if err != nil {
+				panic("invalid weekly flag of closure")
+			}
+		}
+
+		closure := &Disruption{
+			Station: record[0],
+			Line:    record[1],
+			From:    record[2],
+			To:      record[3],
+			Start:   record[4],
+			End:     record[5],
+			Weekly:  weekly,
+			Reason:  record[7],
+		}
+		if closure.Start == "" || closure.End == "" {
+			panic("closure must have start and end time")
+		}
+		if _, err := addDisruption(closure); err != nil {
+			log.Printf("invalid closure on line %v of $CLOSURES_FILE\n", line+1)
+			panic("invalid closure")
+		}
+	})
 }
 
-// populateNeighbours populates neighbours (prev and next) for every line-station.
+// populateNeighbours orders line-stations on every train line by line-station number.
+// Neighbours (prev and next) of a line-station are the adjacent entries in trainLineMap.
 func populateNeighbours(trainLines map[string][]string) {
 	for lineCode, stationCodes := range trainLines {
 		// to find neighbours, first sort line stations by line-station number.
 		sort.Sort(byStationCode(stationCodes))
 
-		edge := createEdge(lineCode)
-
 		for i, stationCode := range stationCodes {
 			lineStationMap[stationCode].lineStationIdx = i
+		}
+		trainLineMap[lineCode] = stationCodes
+	}
+}
 
-			if i-1 >= 0 {
-				lineStationMap[stationCode].neighbours[stationNameMap[lineStationMap[stationCodes[i-1]].name].idx] = edge
-			}
-			if i+1 < len(stationCodes) {
-				lineStationMap[stationCode].neighbours[stationNameMap[lineStationMap[stationCodes[i+1]].name].idx] = edge
-			}
+// addEdge adds given train line to the edge (connection) between two stations, creating the edge if needed.
+// Adjacent stations may be connected by more than one train line e.g. Raffles Place and City Hall.
+func addEdge(adjMatrix adjacencyMatrix, from, to int, lineCode string, w *weight) {
+	e, ok := adjMatrix[from][to]
+	if !ok {
+		e = &edge{
+			disabled: false,
+			lines:    map[string]*weight{},
 		}
+		adjMatrix[from][to] = e
 	}
+	e.lines[lineCode] = w
 }
 
-// createEdge creates an edge (connection) for a given train line.
-func createEdge(lineCode string) *edge {
+// createWeight creates weight of an edge between two line-stations of a given train line.
+// Segment cost is used when available, otherwise train line cost. Service hours are always of the train line.
+func createWeight(lineCode, fromCode, toCode string) *weight {
 	lineCosts, ok := lineCostMap[lineCode]
 	if !ok {
 		log.Printf("Trainline %v cost is not available\n", lineCode)
 		panic("trainline cost not available")
 	}
 
-	return &edge{
-		disabled: false,
-		weight: &weight{
-			nonPeakHour: lineCosts[0],
-			peakHour:    lineCosts[1],
-			nightHour:   lineCosts[2],
-			defaults:    1,
-		},
+	costs := lineCosts
+	if segmentCosts, ok := segmentCostMap[[2]string{fromCode, toCode}]; ok {
+		for i := range costs {
+			if costs[i] != lineNotInService {
+				costs[i] = segmentCosts[i]
+			}
+		}
+	}
+
+	w := &weight{
+		nonPeakHour: costs[0],
+		peakHour:    costs[1],
+		nightHour:   costs[2],
+		defaults:    1,
+	}
+	for i, ht := range lineCostHourTypes {
+		if costs[i] == lineNotInService {
+			w.closedHours = append(w.closedHours, ht)
+		}
+	}
+
+	return w
+}
+
+// createWalkingWeight creates weight of a walking link, which costs the same walking time at all hours.
+func createWalkingWeight(walkingTime int) *weight {
+	return &weight{
+		nonPeakHour: walkingTime,
+		peakHour:    walkingTime,
+		nightHour:   walkingTime,
+		defaults:    1,
+	}
+}
+
+// isInService checks whether train line of the weight is in service during given hour type.
+func (w *weight) isInService(ht types.HourType) bool {
+	for _, closedHour := range w.closedHours {
+		if closedHour == ht {
+			return false
+		}
+	}
+	return true
+}
+
+// getInterchangeCost returns cost of changing between given train lines at given station during given hour type.
+// It falls back to interchange cost of the hour type, if the station has no specific cost.
+func getInterchangeCost(stationIdx int, fromLine, toLine string, ht types.HourType) int {
+	if costs, ok := stationInterchangeCostMap[interchange{stationIdx: stationIdx, fromLine: fromLine, toLine: toLine}]; ok {
+		return costs[ht]
 	}
+	return interchangeCostMap[ht]
+}
+
+// getWaitTime returns expected waiting time for a train of given line during given hour type i.e. half of its headway.
+// Walking and train lines without headway have no waiting time.
+func getWaitTime(lineCode string, ht types.HourType) int {
+	return (lineHeadwayMap[lineCode][ht] + 1) / 2
 }
 
 // initRailNetworkAdjacencyMatrix initializes adjacency matrix of given rail network.
 func initRailNetworkAdjacencyMatrix() {
+	railNetworkAdjacencyMatrix = createAdjacencyMatrix(time.Time{})
+}
+
+// createAdjacencyMatrix creates adjacency matrix of the rail network as on given date.
+// Line-stations not opened by then are left out and their neighbours on the line are connected directly.
+// Walking links connect stations only once both are opened.
+// A zero date includes every line-station.
+func createAdjacencyMatrix(asOf time.Time) adjacencyMatrix {
+	adjMatrix := adjacencyMatrix{}
 	for _, station := range stationNameMap {
-		adj := map[int]*edge{}
-		for _, stationCode := range station.codes {
-			for k, v := range lineStationMap[stationCode].neighbours {
-				adj[k] = v
+		adjMatrix[station.idx] = map[int]*edge{}
+	}
+
+	for lineCode, stationCodes := range trainLineMap {
+		prevIdx, prevCode := -1, ""
+		for _, stationCode := range stationCodes {
+			if !lineStationMap[stationCode].isOpen(asOf) {
+				continue
+			}
+
+			idx := stationNameMap[lineStationMap[stationCode].name].idx
+			if prevIdx >= 0 {
+				w := createWeight(lineCode, prevCode, stationCode)
+				addEdge(adjMatrix, prevIdx, idx, lineCode, w)
+				addEdge(adjMatrix, idx, prevIdx, lineCode, w)
 			}
+			prevIdx, prevCode = idx, stationCode
+		}
+	}
+
+	for link, walkingTime := range walkingLinkMap {
+		if stationIndexMap[link[0]].isOpen(asOf) && stationIndexMap[link[1]].isOpen(asOf) {
+			addEdge(adjMatrix, link[0], link[1], walkLine, createWalkingWeight(walkingTime))
+		}
+	}
+	return adjMatrix
+}
+
+// isOpen checks whether line-station is opened on given date. Zero date is treated as always open.
+func (ls *lineStation) isOpen(asOf time.Time) bool {
+	return asOf.IsZero() || !ls.openingDate.After(asOf)
+}
+
+// isOpen checks whether station is opened on any of its lines on given date.
+func (s *station) isOpen(asOf time.Time) bool {
+	for _, stationCode := range s.codes {
+		if lineStationMap[stationCode].isOpen(asOf) {
+			return true
+		}
+	}
+	return false
+}
+
+// isOnLine checks whether station is on given train line.
+func (s *station) isOnLine(lineCode string) bool {
+	for _, stationCode := range s.codes {
+		if stationCode[:2] == lineCode {
+			return true
+		}
+	}
+	return false
+}
+
+// getCode returns station code of station on given train line. It's empty if station is not on the train line.
+func (s *station) getCode(lineCode string) string {
+	for _, stationCode := range s.codes {
+		if stationCode[:2] == lineCode {
+			return stationCode
+		}
+	}
+	return ""
+}
+
+// getStation returns station of given name or any of its station codes e.g. EW24. Codes are case-insensitive.
+func getStation(nameOrCode string) (*station, bool) {
+	if s, ok := stationNameMap[nameOrCode]; ok {
+		return s, true
+	}
+	if ls, ok := lineStationMap[strings.ToUpper(nameOrCode)]; ok {
+		return stationNameMap[ls.name], true
+	}
+	return nil, false
+}
+
+// openingDate returns the date when station was first opened on any of its lines.
+func (s *station) openingDate() time.Time {
+	var openingDate time.Time
+	for _, stationCode := range s.codes {
+		if d := lineStationMap[stationCode].openingDate; openingDate.IsZero() || d.Before(openingDate) {
+			openingDate = d
+		}
+	}
+	return openingDate
+}
+
+// readMessagesFile reads message templates file and adds its templates to messageCatalog.
+// A template replaces the built-in template of its language and key, and may add a new language.
+// If environment variable is not set, built-in templates are used.
+func readMessagesFile() {
+	readCSVFile("MESSAGES_FILE", false, func(record []string, _ int) {
+		lang, key := strings.ToLower(record[0]), messageKey(record[1])
+		if _, ok := messageCatalog[defaultLanguage][key]; !ok || lang == "" {
+			log.Printf("Message %v of language %v is not valid\n", key, lang)
+			panic("invalid message template")
+		}
+		if _, ok := messageCatalog[lang]; !ok {
+			messageCatalog[lang] = map[messageKey]string{}
+		}
+		messageCatalog[lang][key] = record[2]
+	})
+}
+
+// readStationNamesFile reads localized station names file and initializes localizedStationNameMap.
+// If environment variable is not set, station names are not localized.
+func readStationNamesFile() {
+	readCSVFile("STATION_NAMES_FILE", false, func(record []string, _ int) {
+		lang := strings.ToLower(record[1])
+		if _, ok := stationNameMap[record[0]]; !ok || lang == "" || record[2] == "" {
+			log.Printf("Station %v name in language %v is not valid\n", record[0], lang)
+			panic("invalid localized station name")
+		}
+		if _, ok := localizedStationNameMap[lang]; !ok {
+			localizedStationNameMap[lang] = map[string]string{}
+		}
+		localizedStationNameMap[lang][record[0]] = record[2]
+	})
+}
+
+// readStationAliasesFile reads station aliases file and initializes stationAliasMap.
+// If environment variable is not set, station search matches station names and localized names only.
+func readStationAliasesFile() {
+	readCSVFile("STATION_ALIASES_FILE", false, func(record []string, _ int) {
+		if _, ok := stationNameMap[record[1]]; !ok || record[0] == "" {
+			log.Printf("Station %v alias %v is not valid\n", record[1], record[0])
+			panic("invalid station alias")
 		}
-		railNetworkAdjacencyMatrix[station.idx] = adj
+		stationAliasMap[strings.ToLower(record[0])] = record[1]
+	})
+}
+
+// readTimetableDir reads GTFS-style timetable from directory configured by environment variable.
+// If environment variable is not set, routes are found on the rail network costs.
+func readTimetableDir() {
+	dir := os.Getenv("TIMETABLE_DIR")
+	if dir == "" {
+		return
 	}
+	railTimetable = readTimetable(dir)
 }
 
 // setTopKValue sets the topK value configured from environment variable.
@@ -315,3 +633,26 @@ func setTopKValue() {
 		topK = 1
 	}
 }
+
+// setAlternativesFilter sets the alternatives filter configured from environment variables.
+// An unset or invalid environment variable disables its check.
+func setAlternativesFilter() {
+	alternatives = newAlternativesFilter()
+	if v := os.Getenv("ALTERNATIVES_MAX_EXTRA_COST"); v != "" {
+		maxExtraCost, err := strconv.Atoi(v)
+		if err != nil || maxExtraCost < 0 {
+			log.Println("invalid ALTERNATIVES_MAX_EXTRA_COST. Ignoring it")
+		} else {
+			alternatives.maxExtraCost = maxExtraCost
+		}
+	}
+	if v := os.Getenv("ALTERNATIVES_MAX_SHARED_EDGES"); v != "" {
+		maxSharedEdges, err := strconv.Atoi(v)
+		if err != nil || maxSharedEdges < 0 {
+			log.Println("invalid ALTERNATIVES_MAX_SHARED_EDGES. Ignoring it")
+		} else {
+			alternatives.maxSharedEdges = maxSharedEdges
+		}
+	}
+	alternatives.noLineReuse = os.Getenv("ALTERNATIVES_NO_LINE_REUSE") == "true"
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/types"
+)
+
+// MatrixQuery is the travel matrix request object
+type MatrixQuery struct {
+	Sources      []string        // source station names
+	Destinations []string        // destination station names
+	JourneyTime  time.Time       // journey start time. optional
+	Mode         types.RouteMode // route ranking mode
+}
+
+// TravelMatrix is the travel matrix response object. Cells[i][j] is the route from i-th source to j-th destination.
+type TravelMatrix struct {
+	Sources      []string        `json:"sources"`
+	Destinations []string        `json:"destinations"`
+	Cells        [][]*MatrixCell `json:"cells"`
+}
+
+// MatrixCell is the best route of a source-destination pair. It's nil if no route exist.
+// Cost is number of stops in stops mode, travel time in time mode, and the tie-breaker of interchanges in transfers mode.
+type MatrixCell struct {
+	Cost         int `json:"cost"`
+	Stops        int `json:"stops"`
+	Interchanges int `json:"interchanges"`
+}
+
+// FindMatrix finds best route from every source to every destination, with one search per source.
+// If journeyTime is set, routes only use stations opened and train lines in service at that time.
+func (h *handlerImpl) FindMatrix(query *MatrixQuery) (*TravelMatrix, error) {
+	mode := query.Mode
+	if len(query.Sources) == 0 || len(query.Destinations) == 0 {
+		log.Println("sources and destinations are required")
+		return nil, ErrInvalidRequest
+	}
+
+	if mode == types.RMInvalid || mode == types.RMPareto || (mode == types.RMTime && query.JourneyTime.IsZero()) {
+		log.Printf("invalid route mode %v", mode)
+		return nil, ErrInvalidRequest
+	}
+
+	dstStations := make([]*station, len(query.Destinations))
+	for j, name := range query.Destinations {
+		s, ok := stationNameMap[name]
+		if !ok {
+			log.Printf("invalid destination station %v", name)
+			return nil, ErrInvalidRequest
+		}
+		dstStations[j] = s
+	}
+
+	adjMatrix := createNetworkAdjacencyMatrix(query.JourneyTime)
+	resp := &TravelMatrix{
+		Sources:      query.Sources,
+		Destinations: query.Destinations,
+		Cells:        make([][]*MatrixCell, len(query.Sources)),
+	}
+	for i, name := range query.Sources {
+		srcStation, ok := stationNameMap[name]
+		if !ok {
+			log.Printf("invalid source station %v", name)
+			return nil, ErrInvalidRequest
+		}
+
+		resp.Cells[i] = make([]*MatrixCell, len(dstStations))
+		if _, ok := adjMatrix[srcStation.idx]; !ok || !srcStation.isOpen(query.JourneyTime) {
+			continue // source is not on the network at journey time; so no route exist.
+		}
+
+		// first settled state of a station is its cheapest one.
+		settled, prevMap := h.search(adjMatrix, vertexState{stationIdx: srcStation.idx}, noStation, query.JourneyTime, false, mode, math.MaxInt32)
+		cells := map[int]*MatrixCell{}
+		for _, node := range settled {
+			if _, ok := cells[node.state.stationIdx]; !ok {
+				cells[node.state.stationIdx] = h.prepareMatrixCell(node, prevMap, mode)
+			}
+		}
+		for j, dstStation := range dstStations {
+			resp.Cells[i][j] = cells[dstStation.idx]
+		}
+	}
+	return resp, nil
+}
+
+// prepareMatrixCell prepares matrix cell of the route to given settled state.
+func (h *handlerImpl) prepareMatrixCell(node *minHeapNode, prevMap map[vertexState]vertexState, mode types.RouteMode) *MatrixCell {
+	cell := &MatrixCell{Cost: getRouteCost(node.dist, mode)}
+
+	state := node.state
+	for prev, ok := prevMap[state]; ok; prev, ok = prevMap[state] {
+		cell.Stops++
+		if isInterchange(prev.trainLine, state.line) {
+			cell.Interchanges++
+		}
+		state = prev
+	}
+	return cell
+}
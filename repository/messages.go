@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// messageKey is the key of a message template in message catalog.
+type messageKey string
+
+const (
+	msgTakeLine          messageKey = "take_line"           // args: line, terminus, from station, to station
+	msgTakeTimetableLine messageKey = "take_timetable_line" // args: line, terminus, from station, departure, to station, arrival
+	msgChangeLine        messageKey = "change_line"         // args: from line, to line
+	msgChangeLineTime    messageKey = "change_line_time"    // args: from line, to line, transfer time
+	msgWalk              messageKey = "walk"                // args: from station, to station, walking time
+	msgTravelTime        messageKey = "travel_time"         // args: travel time
+	msgStops             messageKey = "stops"               // args: number of stops
+	msgInterchanges      messageKey = "interchanges"        // args: number of interchanges
+	msgWaitingTime       messageKey = "waiting_time"        // args: waiting time
+	msgLatestDeparture   messageKey = "latest_departure"    // args: departure time
+	msgSeparator         messageKey = "separator"           // separator of heading parts
+	msgStepSeparator     messageKey = "step_separator"      // separator of route steps
+)
+
+// defaultLanguage is the language of route instructions if no requested language is supported.
+const defaultLanguage = "en"
+
+// messageCatalog maps language to its message templates. Templates are fmt formats; a language may reorder
+// arguments with explicit indexes e.g. %[2]v. Missing templates fall back to default language.
+var messageCatalog = map[string]map[messageKey]string{
+	"en": {
+		msgTakeLine:          "Take %v line towards %v from %v to %v.",
+		msgTakeTimetableLine: "Take %v line towards %v from %v at %v to %v at %v.",
+		msgChangeLine:        "Change from %v line to %v line.",
+		msgChangeLineTime:    "Change from %v line to %v line (transfer time: %v).",
+		msgWalk:              "Walk from %v to %v (%v min).",
+		msgTravelTime:        "Expected Travel time: %v",
+		msgStops:             "Number of stops to destination: %v",
+		msgInterchanges:      "Number of interchanges: %v",
+		msgWaitingTime:       "Expected waiting time: %v",
+		msgLatestDeparture:   "Latest departure time: %v",
+		msgSeparator:         ", ",
+		msgStepSeparator:     " ",
+	},
+	"zh": {
+		msgTakeLine:          "乘坐%v线（往%v方向），从%v到%v。",
+		msgTakeTimetableLine: "乘坐%v线（往%v方向），%[4]v从%[3]v出发，%[6]v到达%[5]v。",
+		msgChangeLine:        "从%v线换乘%v线。",
+		msgChangeLineTime:    "从%v线换乘%v线（换乘时间：%v）。",
+		msgWalk:              "从%v步行至%v（%v分钟）。",
+		msgTravelTime:        "预计行程时间：%v",
+		msgStops:             "到达目的地的站数：%v",
+		msgInterchanges:      "换乘次数：%v",
+		msgWaitingTime:       "预计等候时间：%v",
+		msgLatestDeparture:   "最晚出发时间：%v",
+		msgSeparator:         "，",
+		msgStepSeparator:     "",
+	},
+	"ms": {
+		msgTakeLine:          "Naik laluan %v arah %v dari %v ke %v.",
+		msgTakeTimetableLine: "Naik laluan %v arah %v dari %v pada %v ke %v pada %v.",
+		msgChangeLine:        "Tukar dari laluan %v ke laluan %v.",
+		msgChangeLineTime:    "Tukar dari laluan %v ke laluan %v (masa pertukaran: %v).",
+		msgWalk:              "Berjalan kaki dari %v ke %v (%v min).",
+		msgTravelTime:        "Jangkaan masa perjalanan: %v",
+		msgStops:             "Bilangan hentian ke destinasi: %v",
+		msgInterchanges:      "Bilangan pertukaran: %v",
+		msgWaitingTime:       "Jangkaan masa menunggu: %v",
+		msgLatestDeparture:   "Masa bertolak terkini: %v",
+		msgSeparator:         ", ",
+	},
+	"ta": {
+		msgTakeLine:          "%v வழித்தடத்தில் %v நோக்கி %v இலிருந்து %v வரை செல்லவும்.",
+		msgTakeTimetableLine: "%v வழித்தடத்தில் %v நோக்கி %v இலிருந்து %v மணிக்குப் புறப்பட்டு %v வரை %v மணிக்குச் செல்லவும்.",
+		msgChangeLine:        "%v வழித்தடத்திலிருந்து %v வழித்தடத்திற்கு மாறவும்.",
+		msgChangeLineTime:    "%v வழித்தடத்திலிருந்து %v வழித்தடத்திற்கு மாறவும் (மாற்று நேரம்: %v).",
+		msgWalk:              "%v இலிருந்து %v வரை நடக்கவும் (%v நிமிடம்).",
+		msgTravelTime:        "எதிர்பார்க்கப்படும் பயண நேரம்: %v",
+		msgStops:             "சேருமிடம் வரையிலான நிறுத்தங்கள்: %v",
+		msgInterchanges:      "மாற்றங்களின் எண்ணிக்கை: %v",
+		msgWaitingTime:       "எதிர்பார்க்கப்படும் காத்திருப்பு நேரம்: %v",
+		msgLatestDeparture:   "கடைசிப் புறப்பாட்டு நேரம்: %v",
+		msgSeparator:         ", ",
+	},
+}
+
+// localizedStationNameMap maps language to station name to its localized name. optional
+var localizedStationNameMap = map[string]map[string]string{}
+
+// getLanguage returns the first supported language of given preferred languages e.g. "zh" or "zh-SG".
+// If none is supported, it returns default language.
+func getLanguage(preferred []string) string {
+	for _, lang := range preferred {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if i := strings.IndexAny(lang, "-_"); i >= 0 {
+			lang = lang[:i] // primary language subtag
+		}
+		if _, ok := messageCatalog[lang]; ok {
+			return lang
+		}
+	}
+	return defaultLanguage
+}
+
+// message returns message of given key in given language, formatted with given arguments.
+func message(lang string, key messageKey, args ...interface{}) string {
+	template, ok := messageCatalog[lang][key]
+	if !ok {
+		template = messageCatalog[defaultLanguage][key]
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// localizeStation returns name of station in given language. It falls back to the station name.
+func localizeStation(lang, name string) string {
+	if localized, ok := localizedStationNameMap[lang][name]; ok {
+		return localized
+	}
+	return name
+}
+
+// joinMessages joins given heading parts with separator of given language.
+func joinMessages(lang string, parts ...string) string {
+	return strings.Join(parts, message(lang, msgSeparator))
+}
+
+// joinSteps joins given route steps with step separator of given language.
+func joinSteps(lang string, steps []string) string {
+	return strings.Join(steps, message(lang, msgStepSeparator))
+}
@@ -2,11 +2,15 @@ package repository
 
 // minHeapNode is an object for a min-heap node.
 type minHeapNode struct {
-	idx        int // min-heap index
-	dist       int // distance from src
-	stationIdx int // station index
+	idx          int         // min-heap index
+	dist         int         // distance from src
+	elapsed      int         // travel time from src
+	interchanges int         // number of interchanges from src
+	state        vertexState // search state i.e. station and the line used to reach it
 }
 
+// minHeap is a min-heap of nodes ordered by distance. Ties are broken by fewer interchanges, and then by state,
+// so that routes of equal distance are found in the same order every time.
 type minHeap []*minHeapNode
 
 func (pq minHeap) Len() int {
@@ -14,7 +18,16 @@ func (pq minHeap) Len() int {
 }
 
 func (pq minHeap) Less(i, j int) bool {
-	return pq[i].dist < pq[j].dist
+	if pq[i].dist != pq[j].dist {
+		return pq[i].dist < pq[j].dist
+	}
+	if pq[i].interchanges != pq[j].interchanges {
+		return pq[i].interchanges < pq[j].interchanges
+	}
+	if pq[i].state.stationIdx != pq[j].state.stationIdx {
+		return pq[i].state.stationIdx < pq[j].state.stationIdx
+	}
+	return pq[i].state.line < pq[j].state.line
 }
 
 func (pq minHeap) Swap(i, j int) {
@@ -34,5 +47,44 @@ func (pq *minHeap) Pop() interface{} {
 
 func (pq *minHeap) Push(x interface{}) {
 	item := x.(*minHeapNode)
+	item.idx = len(*pq)
+	*pq = append(*pq, item)
+}
+
+// labelHeap is a min-heap of labels ordered by travel time, stops and then interchanges.
+type labelHeap []*label
+
+func (pq labelHeap) Len() int {
+	return len(pq)
+}
+
+func (pq labelHeap) Less(i, j int) bool {
+	if pq[i].time != pq[j].time {
+		return pq[i].time < pq[j].time
+	}
+	if pq[i].stops != pq[j].stops {
+		return pq[i].stops < pq[j].stops
+	}
+	return pq[i].interchanges < pq[j].interchanges
+}
+
+func (pq labelHeap) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+	pq[i].idx = i
+	pq[j].idx = j
+}
+
+func (pq *labelHeap) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*pq = old[0 : n-1]
+	return item
+}
+
+func (pq *labelHeap) Push(x interface{}) {
+	item := x.(*label)
+	item.idx = len(*pq)
 	*pq = append(*pq, item)
 }
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/types"
+)
+
+// label is a partial route to a search state in multi-criteria search.
+type label struct {
+	idx          int         // min-heap index
+	state        vertexState // station and the line used to reach it
+	time         int         // travel time from src
+	stops        int         // number of stops from src
+	interchanges int         // number of interchanges from src
+	prev         *label      // label of previous state on the route
+	dominated    bool        // whether a better label for the same state was found later
+}
+
+// dominates checks whether label is at least as good as other label on every criteria.
+func (l *label) dominates(other *label) bool {
+	return l.time <= other.time && l.stops <= other.stops && l.interchanges <= other.interchanges
+}
+
+// visits checks whether station is already on the route of the label.
+func (l *label) visits(stationIdx int) bool {
+	for ; l != nil; l = l.prev {
+		if l.state.stationIdx == stationIdx {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns states on the route of the label.
+func (l *label) path() []vertexState {
+	route := []vertexState{}
+	for ; l != nil; l = l.prev {
+		route = append([]vertexState{l.state}, route...)
+	}
+	return route
+}
+
+// isDominated checks whether any of the labels dominates given label.
+func isDominated(labels []*label, l *label) bool {
+	for _, other := range labels {
+		if other.dominates(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// pareto finds all Pareto-optimal routes from src to dst on travel time, stops and interchanges
+// using a multi-criteria label-setting search over (station, line) states.
+// Every edge and interchange is charged by the hour type at the clock time the rider reaches it.
+// Returned labels are ordered by travel time.
+func (h *handlerImpl) pareto(adjMatrix adjacencyMatrix, src int, dst int, startTime time.Time) ([]*label, error) {
+	if _, ok := adjMatrix[src]; !ok {
+		return nil, fmt.Errorf("Vertex %v does not exist", src)
+	}
+
+	labels := map[vertexState][]*label{} // non-dominated labels of every state.
+	results := []*label{}                // non-dominated labels of dst.
+	minHeap := labelHeap{}               // min heap to find label with min travel time.
+
+	heap.Push(&minHeap, &label{state: vertexState{stationIdx: src}})
+
+	for minHeap.Len() != 0 {
+		fromLabel := heap.Pop(&minHeap).(*label)
+		if fromLabel.dominated || isDominated(results, fromLabel) {
+			continue
+		}
+
+		from := fromLabel.state
+		if from.stationIdx == dst {
+			results = append(results, fromLabel)
+			continue
+		}
+
+		ht := getHourTypeAt(startTime, fromLabel.time, false)
+		for to, edge := range adjMatrix[from.stationIdx] {
+			if edge.disabled || fromLabel.visits(to) {
+				continue // edge is disabled or route would loop; so skip it.
+			}
+			for nextLine, w := range edge.lines {
+				if !w.isInService(ht) {
+					continue // train line is not in service; so skip it.
+				}
+
+				toLabel := &label{
+					state:        from.next(to, nextLine),
+					time:         fromLabel.time + h.getTravelCost(adjMatrix, from, to, nextLine, ht, false, types.RMTime),
+					stops:        fromLabel.stops + w.defaults,
+					interchanges: fromLabel.interchanges,
+					prev:         fromLabel,
+				}
+				if isInterchange(from.trainLine, nextLine) {
+					toLabel.interchanges++
+				}
+
+				if isDominated(results, toLabel) || isDominated(labels[toLabel.state], toLabel) {
+					continue
+				}
+
+				// drop labels of the state which are dominated by the new label.
+				stateLabels := []*label{toLabel}
+				for _, l := range labels[toLabel.state] {
+					if toLabel.dominates(l) {
+						l.dominated = true
+					} else {
+						stateLabels = append(stateLabels, l)
+					}
+				}
+				labels[toLabel.state] = stateLabels
+				heap.Push(&minHeap, toLabel)
+			}
+		}
+	}
+
+	if len(results) == 0 {
+		return nil, ErrRouteNotFound
+	}
+	return results, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/types"
+)
+
+// noStation is the index of no station. Searching for it visits every reachable state.
+const noStation = -1
+
+// ReachableQuery is the reachable stations request object
+type ReachableQuery struct {
+	Source      string    // source station name
+	JourneyTime time.Time // journey start time
+	MaxMinutes  int       // travel time budget in minutes
+}
+
+// ReachableStation is a station reachable from source within the travel time budget.
+type ReachableStation struct {
+	Station string `json:"station"`
+	Cost    int    `json:"cost"` // expected travel time from source
+	Line    string `json:"line"` // train line code used to arrive, or WALK
+}
+
+// FindReachable finds every station reachable from source within max minutes of travel time starting at journey time,
+// in increasing order of travel time. Source station itself is not returned.
+func (h *handlerImpl) FindReachable(query *ReachableQuery) ([]*ReachableStation, error) {
+	srcStation, ok := stationNameMap[query.Source]
+	if !ok || query.JourneyTime.IsZero() || query.MaxMinutes <= 0 {
+		log.Printf("invalid source %v, journey time %v or max minutes %v", query.Source, query.JourneyTime, query.MaxMinutes)
+		return nil, ErrInvalidRequest
+	}
+
+	if !srcStation.isOpen(query.JourneyTime) {
+		log.Printf("station %v is not opened by %v", srcStation.name, query.JourneyTime)
+		return nil, &RouteNotFoundError{
+			Reason: fmt.Sprintf("%v station opens on %v", srcStation.name, srcStation.openingDate().Format(dateFormat)),
+		}
+	}
+
+	adjMatrix := createNetworkAdjacencyMatrix(query.JourneyTime)
+	if _, ok := adjMatrix[srcStation.idx]; !ok {
+		return []*ReachableStation{}, nil
+	}
+
+	// search every state within budget; first settled state of a station is its cheapest one.
+	settled, _ := h.search(adjMatrix, vertexState{stationIdx: srcStation.idx}, noStation, query.JourneyTime, false, types.RMTime, query.MaxMinutes)
+	resp := []*ReachableStation{}
+	reached := map[int]bool{srcStation.idx: true}
+	for _, node := range settled {
+		if reached[node.state.stationIdx] {
+			continue
+		}
+		reached[node.state.stationIdx] = true
+		resp = append(resp, &ReachableStation{
+			Station: stationIndexMap[node.state.stationIdx].name,
+			Cost:    node.dist,
+			Line:    node.state.line,
+		})
+	}
+
+	// settled states are in order of travel time; break ties by station name.
+	sort.SliceStable(resp, func(i, j int) bool {
+		if resp[i].Cost != resp[j].Cost {
+			return resp[i].Cost < resp[j].Cost
+		}
+		return resp[i].Station < resp[j].Station
+	})
+	return resp, nil
+}
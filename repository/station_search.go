@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+const (
+	maxSearchResults = 10 // max number of stations returned by station search
+	maxSuggestions   = 3  // max number of stations suggested for an unknown station name
+)
+
+// station match ranks in the order stations are returned by station search. Fuzzy matches rank by edit distance.
+const (
+	exactMatch = iota
+	prefixMatch
+	tokenMatch
+	aliasMatch
+	fuzzyMatch
+)
+
+// matchTypes is the name of every station match rank.
+var matchTypes = map[int]string{
+	exactMatch:  "exact",
+	prefixMatch: "prefix",
+	tokenMatch:  "token",
+	aliasMatch:  "alias",
+	fuzzyMatch:  "fuzzy",
+}
+
+// StationNotFoundError is returned when a station name is unknown along with similarly named stations.
+type StationNotFoundError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *StationNotFoundError) Error() string {
+	return fmt.Sprintf("%v: unknown station %v, did you mean %v?", ErrInvalidRequest, e.Name, strings.Join(e.Suggestions, " or "))
+}
+
+// StationMatch is a station matching the station search query.
+type StationMatch struct {
+	Station string   `json:"station"`
+	Codes   []string `json:"codes"`
+	Match   string   `json:"match"` // how station matched: exact, prefix, token, alias or fuzzy
+
+	rank int // match rank. lower is better
+}
+
+// SearchStations finds stations matching given query, best matches first. Matching is case-insensitive on
+// station name prefix, prefix of its words, aliases and localized names, and edit distance of the name.
+func (h *handlerImpl) SearchStations(query string) ([]*StationMatch, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		log.Println("station search query is required")
+		return nil, ErrInvalidRequest
+	}
+
+	aliases := map[string][]string{} // maps station name to its aliases and localized names.
+	for alias, name := range stationAliasMap {
+		aliases[name] = append(aliases[name], alias)
+	}
+	for _, names := range localizedStationNameMap {
+		for name, localized := range names {
+			aliases[name] = append(aliases[name], strings.ToLower(localized))
+		}
+	}
+
+	matches := []*StationMatch{}
+	for name, s := range stationNameMap {
+		rank, ok := matchStation(query, strings.ToLower(name), aliases[name])
+		if !ok {
+			continue
+		}
+		codes := append([]string{}, s.codes...)
+		sort.Strings(codes)
+		matches = append(matches, &StationMatch{Station: name, Codes: codes, rank: rank})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].Station < matches[j].Station
+	})
+	if len(matches) > maxSearchResults {
+		matches = matches[:maxSearchResults]
+	}
+	for _, m := range matches {
+		m.Match = matchTypes[m.rank]
+		if m.rank > fuzzyMatch {
+			m.Match = matchTypes[fuzzyMatch]
+		}
+	}
+	return matches, nil
+}
+
+// matchStation returns rank of station with given lower case name and aliases matching given lower case query,
+// and whether it matches at all.
+func matchStation(query, name string, aliases []string) (int, bool) {
+	switch {
+	case name == query:
+		return exactMatch, true
+	case strings.HasPrefix(name, query):
+		return prefixMatch, true
+	case isTokenMatch(query, name):
+		return tokenMatch, true
+	}
+
+	for _, alias := range aliases {
+		if strings.HasPrefix(alias, query) {
+			return aliasMatch, true
+		}
+	}
+
+	// allow about one typo every four characters.
+	maxDistance := len([]rune(query))/4 + 1
+	if distance := getEditDistance(query, name); distance <= maxDistance {
+		return fuzzyMatch + distance, true
+	}
+	return 0, false
+}
+
+// isTokenMatch checks whether every word of query is a prefix of a word of name.
+func isTokenMatch(query, name string) bool {
+	nameTokens := strings.Fields(name)
+	for _, queryToken := range strings.Fields(query) {
+		found := false
+		for _, nameToken := range nameTokens {
+			if strings.HasPrefix(nameToken, queryToken) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// getEditDistance returns Levenshtein distance between two strings.
+func getEditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost // substitution
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1 // deletion
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1 // insertion
+			}
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// stationNotFound returns error for unknown station name, suggesting similarly named stations.
+// If no station is similar, or the station exists but cannot be used, it returns ErrInvalidRequest.
+func (h *handlerImpl) stationNotFound(name string) error {
+	log.Printf("invalid station %v", name)
+	matches, _ := h.SearchStations(name)
+	if len(matches) == 0 || matches[0].Station == name {
+		return ErrInvalidRequest
+	}
+
+	e := &StationNotFoundError{Name: name}
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		e.Suggestions = append(e.Suggestions, matches[i].Station)
+	}
+	return e
+}
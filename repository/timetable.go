@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"encoding/csv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timetable stores a GTFS-style schedule of the rail network.
+type timetable struct {
+	stops        map[string]*timetableStop        // maps stop_id to stop.
+	stationStops map[string][]string              // maps station name to its stop ids e.g. one per train line.
+	transfers    map[string]map[string]int        // maps from stop_id to to stop_id to min transfer time in seconds.
+	services     map[string]*timetableService     // maps service_id to service calendar.
+	connections  []*connection                    // every hop of every trip ordered by departure time.
+	trips        map[*timetableTrip][]*connection // maps trip to its connections ordered by stop sequence.
+}
+
+// timetableStop stores attributes of a stop (platform) from stops.txt.
+type timetableStop struct {
+	id   string
+	name string
+}
+
+// timetableTrip stores attributes of a trip from trips.txt.
+type timetableTrip struct {
+	id        string
+	line      string // train line code i.e. route_short_name of trip's route.
+	serviceID string
+}
+
+// timetableService stores the days a service runs from calendar.txt and calendar_dates.txt.
+type timetableService struct {
+	weekdays  [7]bool         // indexed by time.Weekday.
+	startDate string          // YYYYMMDD
+	endDate   string          // YYYYMMDD
+	added     map[string]bool // YYYYMMDD dates service runs in addition to weekdays.
+	removed   map[string]bool // YYYYMMDD dates service does not run.
+}
+
+// connection is a trip travelling from a stop to its next stop without stopping in between.
+type connection struct {
+	trip      *timetableTrip
+	fromStop  string
+	toStop    string
+	departure int // seconds since service day midnight. It can be past 24:00.
+	arrival   int // seconds since service day midnight. It can be past 24:00.
+}
+
+// gtfsDateFormat is the date format used in GTFS calendar files.
+const gtfsDateFormat = "20060102"
+
+// runsOn checks whether service runs on given date.
+func (s *timetableService) runsOn(date time.Time) bool {
+	d := date.Format(gtfsDateFormat)
+	if s.removed[d] {
+		return false
+	}
+	if s.added[d] {
+		return true
+	}
+	return s.weekdays[date.Weekday()] && s.startDate <= d && d <= s.endDate
+}
+
+// readTimetable reads GTFS-style timetable files from given directory.
+// Required files are stops.txt, routes.txt, trips.txt, stop_times.txt and calendar.txt.
+// calendar_dates.txt and transfers.txt are optional.
+func readTimetable(dir string) *timetable {
+	tt := &timetable{
+		stops:        map[string]*timetableStop{},
+		stationStops: map[string][]string{},
+		transfers:    map[string]map[string]int{},
+		services:     map[string]*timetableService{},
+	}
+
+	for _, record := range readGTFSFile(dir, "stops.txt", true) {
+		stop := &timetableStop{id: record["stop_id"], name: record["stop_name"]}
+		tt.stops[stop.id] = stop
+		tt.stationStops[stop.name] = append(tt.stationStops[stop.name], stop.id)
+	}
+
+	routeLines := map[string]string{} // maps route_id to train line code.
+	for _, record := range readGTFSFile(dir, "routes.txt", true) {
+		routeLines[record["route_id"]] = record["route_short_name"]
+	}
+
+	trips := map[string]*timetableTrip{}
+	for _, record := range readGTFSFile(dir, "trips.txt", true) {
+		trips[record["trip_id"]] = &timetableTrip{
+			id:        record["trip_id"],
+			line:      routeLines[record["route_id"]],
+			serviceID: record["service_id"],
+		}
+	}
+
+	weekdayColumns := [...]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+	for _, record := range readGTFSFile(dir, "calendar.txt", true) {
+		service := &timetableService{
+			startDate: record["start_date"],
+			endDate:   record["end_date"],
+			added:     map[string]bool{},
+			removed:   map[string]bool{},
+		}
+		for i, column := range weekdayColumns {
+			service.weekdays[i] = record[column] == "1"
+		}
+		tt.services[record["service_id"]] = service
+	}
+
+	for _, record := range readGTFSFile(dir, "calendar_dates.txt", false) {
+		service, ok := tt.services[record["service_id"]]
+		if !ok {
+			log.Printf("unknown service %v in calendar_dates.txt. Skipping it\n", record["service_id"])
+			continue
+		}
+		switch record["exception_type"] {
+		case "1":
+			service.added[record["date"]] = true
+		case "2":
+			service.removed[record["date"]] = true
+		}
+	}
+
+	for _, record := range readGTFSFile(dir, "transfers.txt", false) {
+		transferTime, err := strconv.Atoi(record["min_transfer_time"])
+		if err != nil || transferTime < 0 {
+			panic("invalid min transfer time in transfers.txt")
+		}
+		if _, ok := tt.transfers[record["from_stop_id"]]; !ok {
+			tt.transfers[record["from_stop_id"]] = map[string]int{}
+		}
+		tt.transfers[record["from_stop_id"]][record["to_stop_id"]] = transferTime
+	}
+
+	tt.connections, tt.trips = readConnections(dir, trips)
+	return tt
+}
+
+// stopTime stores a row of stop_times.txt.
+type stopTime struct {
+	stopID    string
+	sequence  int
+	arrival   int
+	departure int
+}
+
+// readConnections reads stop_times.txt and returns connections of every trip ordered by departure time,
+// along with connections indexed by trip in stop sequence order.
+func readConnections(dir string, trips map[string]*timetableTrip) ([]*connection, map[*timetableTrip][]*connection) {
+	tripStopTimes := map[string][]*stopTime{}
+	for _, record := range readGTFSFile(dir, "stop_times.txt", true) {
+		sequence, err := strconv.Atoi(record["stop_sequence"])
+		if err != nil {
+			panic("invalid stop sequence in stop_times.txt")
+		}
+		tripStopTimes[record["trip_id"]] = append(tripStopTimes[record["trip_id"]], &stopTime{
+			stopID:    record["stop_id"],
+			sequence:  sequence,
+			arrival:   parseGTFSTime(record["arrival_time"]),
+			departure: parseGTFSTime(record["departure_time"]),
+		})
+	}
+
+	connections := []*connection{}
+	tripConnections := map[*timetableTrip][]*connection{}
+	for tripID, stopTimes := range tripStopTimes {
+		trip, ok := trips[tripID]
+		if !ok {
+			log.Printf("unknown trip %v in stop_times.txt. Skipping it\n", tripID)
+			continue
+		}
+
+		sort.Slice(stopTimes, func(i, j int) bool {
+			return stopTimes[i].sequence < stopTimes[j].sequence
+		})
+		for i := 0; i+1 < len(stopTimes); i++ {
+			c := &connection{
+				trip:      trip,
+				fromStop:  stopTimes[i].stopID,
+				toStop:    stopTimes[i+1].stopID,
+				departure: stopTimes[i].departure,
+				arrival:   stopTimes[i+1].arrival,
+			}
+			connections = append(connections, c)
+			tripConnections[trip] = append(tripConnections[trip], c)
+		}
+	}
+
+	sort.SliceStable(connections, func(i, j int) bool {
+		return connections[i].departure < connections[j].departure
+	})
+	return connections, tripConnections
+}
+
+// parseGTFSTime parses HH:MM:SS time into seconds since midnight. Hours can be past 24.
+func parseGTFSTime(str string) int {
+	parts := strings.Split(strings.TrimSpace(str), ":")
+	if len(parts) != 3 {
+		panic("invalid time " + str + " in stop_times.txt")
+	}
+
+	seconds := 0
+	for _, part := range parts {
+		val, err := strconv.Atoi(part)
+		if err != nil {
+			panic("invalid time " + str + " in stop_times.txt")
+		}
+		seconds = seconds*60 + val
+	}
+	return seconds
+}
+
+// readGTFSFile reads a GTFS file and returns its records as column name to value maps.
+// A missing optional file returns no records.
+func readGTFSFile(dir, name string, required bool) []map[string]string {
+	file, err := os.Open(filepath.Join(dir, name))
+	if os.IsNotExist(err) && !required {
+		return nil
+	}
+	if err != nil {
+		panic("error while opening timetable file " + name)
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+
+	var header []string
+	records := []map[string]string{}
+	// Iterate through the records
+	for {
+		// Read each record from csv
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if header == nil {
+			header = record // first line is the header line
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+			continue
+		}
+
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = strings.TrimSpace(record[i])
+			}
+		}
+		records = append(records, row)
+	}
+	return records
+}
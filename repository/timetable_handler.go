@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/types"
+)
+
+// railTimetable is the schedule of the rail network. It's nil unless $TIMETABLE_DIR is set.
+var railTimetable *timetable
+
+// timetableHandlerImpl is a implementation of Handler interface which finds routes on the timetable.
+// It falls back to the rail network handler for everything else.
+type timetableHandlerImpl struct {
+	*handlerImpl
+	timetable *timetable
+}
+
+// FindRoutes finds the earliest arrival route from source to destination departing at journey time.
+// Queries the timetable does not support i.e. other route modes, via stations, arrival time or more than one route,
+// are routed on the rail network instead. A timetable query asks for a single route, so fewer routes never exist.
+func (h *timetableHandlerImpl) FindRoutes(query *RouteQuery) ([]*Route, bool, error) {
+	if query.Mode != types.RMTime || query.JourneyTime.IsZero() || !query.ArriveBy.IsZero() || len(query.Via) > 0 || query.K > 1 {
+		log.Printf("route mode %v with via stations %v is not supported by timetable; routing on rail network", query.Mode, query.Via)
+		return h.handlerImpl.FindRoutes(query)
+	}
+
+	src, dst := getStationName(query.Source), getStationName(query.Destination)
+	srcStops, ok := h.timetable.stationStops[src]
+	if !ok {
+		return nil, false, h.stationNotFound(query.Source)
+	}
+	dstStops, ok := h.timetable.stationStops[dst]
+	if !ok {
+		return nil, false, h.stationNotFound(query.Destination)
+	}
+
+	avoidStations := map[string]bool{}
+	for _, name := range query.AvoidStations {
+		avoidStations[getStationName(name)] = true
+	}
+	avoidLines := map[string]bool{}
+	for _, lineCode := range query.AvoidLines {
+		avoidLines[lineCode] = true
+	}
+
+	journeyTime := query.JourneyTime
+	serviceDay := time.Date(journeyTime.Year(), journeyTime.Month(), journeyTime.Day(), 0, 0, 0, 0, journeyTime.Location())
+	departure := int(journeyTime.Sub(serviceDay).Seconds())
+
+	disruptionMutex.RLock()
+	disruptions := getDisruptions(journeyTime)
+	disruptionMutex.RUnlock()
+
+	active := func(c *connection, offset int) bool {
+		service, ok := h.timetable.services[c.trip.serviceID]
+		from, to := h.timetable.stops[c.fromStop].name, h.timetable.stops[c.toStop].name
+		return ok && service.runsOn(serviceDay.AddDate(0, 0, -offset/secondsPerDay)) && !avoidLines[c.trip.line] &&
+			!avoidStations[from] && !avoidStations[to] && !isClosed(disruptions, from, to, c.trip.line)
+	}
+
+	legs, err := h.timetable.csa(srcStops, dstStops, departure, active, interchangeCostMap[types.GetHourType(journeyTime)]*60)
+	if err != nil {
+		log.Printf("failed to find route from %v to dst %v, err: %v", query.Source, query.Destination, err)
+		return nil, false, err
+	}
+
+	arrival := legs[len(legs)-1].arrival
+	lang := query.language()
+	route := &Route{
+		Source:       src,
+		Destination:  dst,
+		Heading:      message(lang, msgTravelTime, (arrival-departure+59)/60),
+		Steps:        h.prepareTimetableSteps(legs, lang),
+		Cost:         (arrival - departure + 59) / 60,
+		Interchanges: len(legs) - 1,
+		Departure:    formatTime(journeyTime),
+		Legs:         h.prepareTimetableLegs(legs, serviceDay, departure),
+	}
+	for _, leg := range route.Legs {
+		route.Stops = route.Stops + leg.Stops
+	}
+	return []*Route{route}, false, nil
+}
+
+// prepareTimetableLegs prepares structured legs of timetable route departing at given seconds since midnight of
+// service day. Waiting time of a leg is the time from reaching its board stop until its departure, including any transfer.
+func (h *timetableHandlerImpl) prepareTimetableLegs(legs []*timetableLeg, serviceDay time.Time, departure int) []*RouteLeg {
+	routeLegs := make([]*RouteLeg, len(legs))
+	reached := departure // seconds since midnight the rider reaches board stop of the leg.
+	for i, leg := range legs {
+		board, alight := h.timetable.stops[leg.fromStop].name, h.timetable.stops[leg.toStop].name
+		routeLegs[i] = &RouteLeg{
+			Line:                 leg.line,
+			Terminus:             h.timetable.stops[leg.terminus].name,
+			BoardStation:         board,
+			BoardCode:            getStationCode(board, leg.line),
+			AlightStation:        alight,
+			AlightCode:           getStationCode(alight, leg.line),
+			IntermediateStations: []string{},
+			Stops:                len(leg.stops) + 1,
+			Duration:             (leg.arrival - leg.departure + 59) / 60,
+			WaitTime:             (leg.departure - reached + 59) / 60,
+			Departure:            formatTime(serviceDay.Add(time.Duration(leg.departure) * time.Second)),
+			Arrival:              formatTime(serviceDay.Add(time.Duration(leg.arrival) * time.Second)),
+		}
+		for _, stop := range leg.stops {
+			routeLegs[i].IntermediateStations = append(routeLegs[i].IntermediateStations, h.timetable.stops[stop].name)
+		}
+		reached = leg.arrival
+	}
+	return routeLegs
+}
+
+// getStationName returns name of station of given name or code, or given name if the rail network does not have it.
+func getStationName(nameOrCode string) string {
+	if s, ok := getStation(nameOrCode); ok {
+		return s.name
+	}
+	return nameOrCode
+}
+
+// getStationCode returns station code of named station on given train line, if the rail network has it.
+func getStationCode(name, lineCode string) string {
+	if s, ok := stationNameMap[name]; ok {
+		return s.getCode(lineCode)
+	}
+	return ""
+}
+
+// prepareTimetableSteps prepares and returns detailed timetable route in string format in given language.
+func (h *timetableHandlerImpl) prepareTimetableSteps(legs []*timetableLeg, lang string) string {
+	steps := []string{}
+	for i, leg := range legs {
+		if i > 0 {
+			steps = append(steps, message(lang, msgChangeLine, legs[i-1].line, leg.line))
+		}
+		steps = append(steps, message(lang, msgTakeTimetableLine, leg.line, h.localizeStop(leg.terminus, lang),
+			h.localizeStop(leg.fromStop, lang), formatGTFSTime(leg.departure),
+			h.localizeStop(leg.toStop, lang), formatGTFSTime(leg.arrival)))
+	}
+	return joinSteps(lang, steps)
+}
+
+// localizeStop returns station name of given stop in given language.
+func (h *timetableHandlerImpl) localizeStop(stop, lang string) string {
+	return localizeStation(lang, h.timetable.stops[stop].name)
+}
+
+// formatGTFSTime formats seconds since service day midnight as HH:MM clock time.
+func formatGTFSTime(seconds int) string {
+	return fmt.Sprintf("%02d:%02d", (seconds/3600)%24, (seconds/60)%60)
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rahulbharuka/train-route-finder/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimetableFindRoutes(t *testing.T) {
+	h := &timetableHandlerImpl{
+		handlerImpl: &handlerImpl{},
+		timetable:   readTimetable("testdata/timetable"),
+	}
+
+	t.Run("weekday-route", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:00")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, []*Route{
+			&Route{
+				Source:       "Jurong East",
+				Destination:  "Holland Village",
+				Departure:    "2022-01-31T08:00",
+				Heading:      "Expected Travel time: 23",
+				Steps:        "Take EW line towards Buona Vista from Jurong East at 08:00 to Buona Vista at 08:09. Change from EW line to CC line. Take CC line towards Farrer Road from Buona Vista at 08:20 to Holland Village at 08:23.",
+				Cost:         23,
+				Stops:        4,
+				Interchanges: 1,
+				Legs: []*RouteLeg{
+					&RouteLeg{
+						Line:                 "EW",
+						Terminus:             "Buona Vista",
+						BoardStation:         "Jurong East",
+						BoardCode:            "EW24",
+						AlightStation:        "Buona Vista",
+						AlightCode:           "EW21",
+						IntermediateStations: []string{"Clementi", "Dover"},
+						Stops:                3,
+						Duration:             9,
+						Departure:            "2022-01-31T08:00",
+						Arrival:              "2022-01-31T08:09",
+					},
+					&RouteLeg{
+						Line:                 "CC",
+						Terminus:             "Farrer Road",
+						BoardStation:         "Buona Vista",
+						BoardCode:            "CC22",
+						AlightStation:        "Holland Village",
+						AlightCode:           "CC21",
+						IntermediateStations: []string{},
+						Stops:                1,
+						Duration:             3,
+						WaitTime:             11,
+						Departure:            "2022-01-31T08:20",
+						Arrival:              "2022-01-31T08:23",
+					},
+				},
+			},
+		}, routes)
+	})
+
+	t.Run("station-codes", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "EW24", Destination: "cc21", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Jurong East", routes[0].Source)
+		assert.Equal(t, "Holland Village", routes[0].Destination)
+		assert.Equal(t, 23, routes[0].Cost)
+	})
+
+	t.Run("calendar-date-exception", func(t *testing.T) {
+		// weekend service runs instead of weekday service on 1 February 2022.
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-02-01T08:00")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 24", routes[0].Heading)
+		assert.Equal(t, "Take EW line towards Buona Vista from Jurong East at 08:00 to Buona Vista at 08:12. Change from EW line to CC line. Take CC line towards Farrer Road from Buona Vista at 08:20 to Holland Village at 08:24.", routes[0].Steps)
+	})
+
+	t.Run("missed-last-connection", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:05")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.EqualError(t, ErrRouteNotFound, err.Error())
+		assert.Nil(t, routes)
+	})
+
+	t.Run("previous-service-day-trip", func(t *testing.T) {
+		// weekday trip of 31 January 2022 departs at 24:50 i.e. 00:50 on 1 February 2022.
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-02-01T00:45")
+
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Buona Vista", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "Expected Travel time: 14", routes[0].Heading)
+		assert.Equal(t, "Take EW line towards Buona Vista from Jurong East at 00:50 to Buona Vista at 00:59.", routes[0].Steps)
+		assert.Equal(t, 5, routes[0].Legs[0].WaitTime)
+		assert.Equal(t, "2022-02-01T00:50", routes[0].Legs[0].Departure)
+		assert.Equal(t, "2022-02-01T00:59", routes[0].Legs[0].Arrival)
+
+		// no weekday trip runs past 24:00 on Sunday 30 January 2022.
+		journeyTime, _ = time.Parse("2006-01-02T15:04", "2022-01-31T00:45")
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Buona Vista", JourneyTime: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Equal(t, "2022-01-31T08:00", routes[0].Legs[0].Departure)
+	})
+
+	t.Run("default-route-count", func(t *testing.T) {
+		// route count left unset by the client is not a request for more than one route.
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:00")
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime, K: 0})
+		assert.NoError(t, err)
+		assert.Len(t, routes, 1)
+		assert.Equal(t, "2022-01-31T08:09", routes[0].Legs[0].Arrival)
+
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime, K: 1})
+		assert.NoError(t, err)
+		assert.Len(t, routes, 1)
+		assert.Equal(t, "2022-01-31T08:09", routes[0].Legs[0].Arrival)
+	})
+
+	t.Run("unsupported-query-on-rail-network", func(t *testing.T) {
+		expected, _, err := h.handlerImpl.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", Mode: types.RMStops})
+		assert.NoError(t, err)
+		routes, _, err := h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", Mode: types.RMStops})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, routes)
+		assert.Equal(t, "Number of stops to destination: 4", routes[0].Heading)
+
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2022-01-31T08:00")
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", JourneyTime: journeyTime, Mode: types.RMTime, K: 2})
+		assert.NoError(t, err)
+		assert.Len(t, routes, 2)
+
+		routes, _, err = h.FindRoutes(&RouteQuery{Source: "Jurong East", Destination: "Holland Village", ArriveBy: journeyTime, Mode: types.RMTime})
+		assert.NoError(t, err)
+		assert.Contains(t, routes[0].Heading, "Latest departure time")
+	})
+}
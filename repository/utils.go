@@ -1,7 +1,7 @@
 package repository
 
 // isShareRootPath check whether path is shared with root.
-func isShareRootPath(path, rootPath []int) bool {
+func isShareRootPath(path, rootPath []vertexState) bool {
 	if len(path) < len(rootPath) {
 		return false
 	}
@@ -9,14 +9,14 @@ func isShareRootPath(path, rootPath []int) bool {
 	return isSamePath(path[:len(rootPath)], rootPath)
 }
 
-// isSamePath checks whether two paths are same.
-func isSamePath(path1, path2 []int) bool {
+// isSamePath checks whether two paths visit the same stations.
+func isSamePath(path1, path2 []vertexState) bool {
 	if len(path1) != len(path2) {
 		return false
 	}
 
 	for i := 0; i < len(path1); i++ {
-		if path1[i] != path2[i] {
+		if path1[i].stationIdx != path2[i].stationIdx {
 			return false
 		}
 	}
@@ -25,8 +25,8 @@ func isSamePath(path1, path2 []int) bool {
 }
 
 // mergePath merges two path.
-func mergePath(path1, path2 []int) []int {
-	newPath := []int{}
+func mergePath(path1, path2 []vertexState) []vertexState {
+	newPath := []vertexState{}
 	newPath = append(newPath, path1...)
 	newPath = append(newPath, path2...)
 
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"log"
+
+	"github.com/rahulbharuka/train-route-finder/types"
+)
+
+// findViaRoute finds a route passing through given ordered stops by chaining shortest route between consecutive stops.
+// Stations of earlier segments are avoided by later segments, so the route has no loops where possible.
+func (h *handlerImpl) findViaRoute(adjMatrix adjacencyMatrix, stops []*station, query *RouteQuery) ([]*Route, error) {
+	journeyTime, mode, lang := query.JourneyTime, query.Mode, query.language()
+
+	segments := []*RouteSegment{}
+	path := []vertexState{{stationIdx: stops[0].idx}}
+	totalDist := 0
+	for i := 0; i+1 < len(stops); i++ {
+		src, dst := stops[i].idx, stops[i+1].idx
+
+		// segment starts at the clock time the route so far reaches its first stop.
+		segmentTime := journeyTime
+		if !journeyTime.IsZero() {
+			segmentTime = addMinutes(journeyTime, h.getPathWeight(adjMatrix, path, journeyTime, false, types.RMTime), false)
+		}
+
+		// segment starts on the state the route so far ends in, so an interchange at its first stop is charged.
+		srcState := path[len(path)-1]
+		h.disablePath(adjMatrix, path[:len(path)-1])
+		dist, segmentPath, err := h.dijkstra(adjMatrix, srcState, dst, segmentTime, false, mode)
+		h.reset(adjMatrix)
+		if err == ErrRouteNotFound {
+			// no loopless route exist; so allow passing through stations of earlier segments.
+			dist, segmentPath, err = h.dijkstra(adjMatrix, srcState, dst, segmentTime, false, mode)
+		}
+		if err == ErrRouteNotFound {
+			err = h.explainRouteNotFound(src, dst, query)
+		}
+		if err != nil {
+			log.Printf("failed to find route from %v to dst %v, err: %v", stops[i].name, stops[i+1].name, err)
+			return nil, err
+		}
+
+		// segment waiting time depends on the train line the route so far ends on.
+		waitTime := h.getPathWaitTime(adjMatrix, path, journeyTime)
+		path = append(path, segmentPath[1:]...)
+		waitTime = h.getPathWaitTime(adjMatrix, path, journeyTime) - waitTime
+
+		segments = append(segments, &RouteSegment{
+			From:    stops[i].name,
+			To:      stops[i+1].name,
+			Heading: h.prepareRouteHeading(dist, waitTime, journeyTime, mode, lang),
+			Steps:   h.prepareRouteSteps(h.prepareRouteLegs(adjMatrix, segmentPath, segmentTime), segmentTime, lang),
+		})
+
+		totalDist = totalDist + dist
+	}
+
+	route := h.prepareRoute(adjMatrix, path, journeyTime, getRouteCost(totalDist, mode), lang)
+	route.Heading = h.prepareRouteHeading(totalDist, h.getPathWaitTime(adjMatrix, path, journeyTime), journeyTime, mode, lang)
+	route.Segments = segments
+	return []*Route{route}, nil
+}
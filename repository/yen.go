@@ -8,52 +8,54 @@ import (
 	"github.com/rahulbharuka/train-route-finder/types"
 )
 
+// interchangePenalty is the cost of an interchange in transfers mode. It's higher than travel cost of any route.
+const interchangePenalty = 100000
+
 // potential is an object for potential shortest path.
 type potential struct {
 	dist int
-	path []int
+	path []vertexState
 }
 
-// Yen returns top-k shortest path from src to dst using Yen's algorithm.
-func (h *handlerImpl) yen(adjMatrix adjacencyMatrix, src int, dst int, topK int, journeyTime time.Time, computeTimeCost bool) ([]int, [][]int, error) {
+// Yen returns top-k shortest path from src to dst using Yen's algorithm. If fewer than k loopless paths exist,
+// it returns all of them, and whether it ran out of loopless paths.
+// In a backward search, src is the journey destination and journeyTime is the arrival time there.
+func (h *handlerImpl) yen(adjMatrix adjacencyMatrix, src int, dst int, topK int, journeyTime time.Time, backward bool, mode types.RouteMode) ([]int, [][]vertexState, bool, error) {
 	var potentials []potential
-	distTopK := make([]int, topK)
-	pathTopK := make([][]int, topK)
-	for i := 0; i < topK; i++ {
-		distTopK[i] = math.MaxInt32
-	}
-
-	ht := types.HTInvalid
-	if computeTimeCost {
-		ht = types.GetHourType(journeyTime)
-	}
 
 	// find the first shortest path
-	dist, dijkstraPrev, err := h.dijkstra(adjMatrix, src, dst, ht, computeTimeCost)
+	dist, path, err := h.dijkstra(adjMatrix, vertexState{stationIdx: src}, dst, journeyTime, backward, mode)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, false, err
 	}
-	distTopK[0] = dist
-	path := []int{}
-	h.prepareDijkstraPath(dst, dijkstraPrev, &path)
-	pathTopK[0] = path // store first shortest path
+	distTopK := []int{dist}
+	pathTopK := [][]vertexState{path} // store first shortest path
 
 	// now run Yen's algorithm for topK-1 times
 	for k := 1; k < topK; {
 		for i := 0; i < len(pathTopK[k-1])-1; i++ {
 			for j := 0; j < k; j++ {
 				if isShareRootPath(pathTopK[j], pathTopK[k-1][:i+1]) {
-					adjMatrix[pathTopK[j][i]][pathTopK[j][i+1]].disabled = true
+					adjMatrix[pathTopK[j][i].stationIdx][pathTopK[j][i+1].stationIdx].disabled = true
 				}
 			}
 			h.disablePath(adjMatrix, pathTopK[k-1][:i])
 
-			dist, dijkstraPrev, _ := h.dijkstra(adjMatrix, pathTopK[k-1][i], dst, ht, computeTimeCost)
+			// spur search starts on the line used to reach the spur node, so an interchange there is charged.
+			// It also starts at the clock time the root path reaches the spur node.
+			spurTime := journeyTime
+			if i > 0 && !journeyTime.IsZero() {
+				rootTime := h.getPathWeight(adjMatrix, pathTopK[k-1][:i+1], journeyTime, backward, types.RMTime)
+				spurTime = addMinutes(journeyTime, rootTime, backward)
+			}
+			dist, sPath, _ := h.dijkstra(adjMatrix, pathTopK[k-1][i], dst, spurTime, backward, mode)
 			if dist != math.MaxInt32 {
-				sPath := []int{}
-				h.prepareDijkstraPath(dst, dijkstraPrev, &sPath)
 				spurPath := mergePath(pathTopK[k-1][:i], sPath)
-				spurWeight := h.getPathWeight(adjMatrix, spurPath, ht, computeTimeCost)
+				spurWeight := h.getPathWeight(adjMatrix, spurPath, journeyTime, backward, mode)
+				if spurWeight == math.MaxInt32 {
+					h.reset(adjMatrix)
+					continue // spur path uses an edge which is not in service.
+				}
 				existed := false
 				for _, each := range potentials {
 					if isSamePath(each.path, spurPath) {
@@ -73,7 +75,7 @@ func (h *handlerImpl) yen(adjMatrix adjacencyMatrix, src int, dst int, topK int,
 		}
 
 		if len(potentials) == 0 {
-			break
+			return distTopK, pathTopK, true, nil // no more loopless path exist.
 		}
 		sort.Slice(potentials, func(i, j int) bool {
 			return potentials[i].dist < potentials[j].dist
@@ -81,26 +83,26 @@ func (h *handlerImpl) yen(adjMatrix adjacencyMatrix, src int, dst int, topK int,
 
 		if len(potentials) >= topK-k {
 			for l := 0; k < topK; l++ {
-				distTopK[k] = potentials[l].dist
-				pathTopK[k] = potentials[l].path
+				distTopK = append(distTopK, potentials[l].dist)
+				pathTopK = append(pathTopK, potentials[l].path)
 				k++
 			}
 			break
 		} else {
-			distTopK[k] = potentials[0].dist
-			pathTopK[k] = potentials[0].path
+			distTopK = append(distTopK, potentials[0].dist)
+			pathTopK = append(pathTopK, potentials[0].path)
 			potentials = potentials[1:]
 			k++
 		}
 	}
 
-	return distTopK, pathTopK, nil
+	return distTopK, pathTopK, len(pathTopK) < topK, nil
 }
 
 // DisablePath disables all the vertices in the path for further calculation.
-func (h *handlerImpl) disablePath(adjMatrix adjacencyMatrix, path []int) {
-	for _, vertex := range path {
-		h.disableVertex(adjMatrix, vertex)
+func (h *handlerImpl) disablePath(adjMatrix adjacencyMatrix, path []vertexState) {
+	for _, state := range path {
+		h.disableVertex(adjMatrix, state.stationIdx)
 	}
 }
 
@@ -111,54 +113,138 @@ func (h *handlerImpl) disableVertex(adjMatrix adjacencyMatrix, vertex int) {
 	}
 }
 
-// getPathWeight returns weight of given path.
-func (h *handlerImpl) getPathWeight(adjMatrix adjacencyMatrix, path []int, ht types.HourType, computeTimeCost bool) int {
+// getPathWeight returns weight of given path starting at given clock time.
+// In a backward search, path starts at the journey destination and the clock goes back in time.
+func (h *handlerImpl) getPathWeight(adjMatrix adjacencyMatrix, path []vertexState, startTime time.Time, backward bool, mode types.RouteMode) int {
 	if len(path) == 0 {
 		return math.MinInt32
 	}
 
-	if _, ok := adjMatrix[path[0]]; !ok {
+	if _, ok := adjMatrix[path[0].stationIdx]; !ok {
 		return math.MinInt32
 	}
 
 	pathWeight := 0
-	prevLine := ""
-	nextLine := ""
+	elapsed := 0
 	for i := 0; i < len(path)-1; i++ {
-		if _, ok := adjMatrix[path[i+1]]; !ok {
+		from, to := path[i], path[i+1]
+		ht := getHourTypeAt(startTime, elapsed, backward)
+		if _, ok := adjMatrix[to.stationIdx]; !ok {
 			return math.MinInt32
 		}
 
-		if _, ok := adjMatrix[path[i]][path[i+1]]; ok {
-			interchangeCost := 0
-			if computeTimeCost {
-				nextLine = h.getTrainLine(path[i], path[i+1])
-				if prevLine != "" && nextLine != prevLine {
-					interchangeCost = interchangeCostMap[ht]
-				}
-				prevLine = nextLine
-			}
-			pathWeight = pathWeight + h.getEdgeWeight(adjMatrix, path[i], path[i+1], ht) + interchangeCost
-		} else {
+		e, ok := adjMatrix[from.stationIdx][to.stationIdx]
+		if !ok {
 			return math.MaxInt32
 		}
+		w, ok := e.lines[to.line]
+		if !ok || !w.isInService(ht) {
+			return math.MaxInt32
+		}
+
+		pathWeight = pathWeight + h.getTravelCost(adjMatrix, from, to.stationIdx, to.line, ht, backward, mode)
+		elapsed = elapsed + h.getTravelCost(adjMatrix, from, to.stationIdx, to.line, ht, backward, types.RMTime)
+		if backward && i+2 == len(path) && mode != types.RMStops {
+			pathWeight = pathWeight + getWaitTime(to.line, ht) // first boarding at journey source.
+		}
 	}
 
 	return pathWeight
 }
 
-// getEdgeWeight returns weight of an edge.
-func (h *handlerImpl) getEdgeWeight(adjMatrix adjacencyMatrix, i, j int, ht types.HourType) int {
+// getPathWaitTime returns expected waiting time for trains on given path starting at given clock time,
+// at first boarding and at every interchange.
+func (h *handlerImpl) getPathWaitTime(adjMatrix adjacencyMatrix, path []vertexState, startTime time.Time) int {
+	waitTime := 0
+	elapsed := 0
+	for i := 0; i+1 < len(path); i++ {
+		from, to := path[i], path[i+1]
+		ht := getHourTypeAt(startTime, elapsed, false)
+		if from.line == "" || isInterchange(from.line, to.line) {
+			waitTime = waitTime + getWaitTime(to.line, ht)
+		}
+		elapsed = elapsed + h.getTravelCost(adjMatrix, from, to.stationIdx, to.line, ht, false, types.RMTime)
+	}
+	return waitTime
+}
+
+// getTravelCost returns cost of travelling from state to station j on given train line for given route mode,
+// including interchange cost if the station of state was reached by a different train line.
+// In a backward search, the rider changes from given train line to the previous one.
+// Walking costs its walking time; it never has an interchange cost of its own.
+// Expected waiting time for the train is charged at first boarding and at every interchange. In a backward search,
+// first boarding is at the journey source, so it is charged by the caller.
+// In stops mode every edge costs 1. In transfers mode every interchange costs interchangePenalty,
+// and travel time (or stops, when hour type is invalid) breaks the tie. Walking between two train lines is
+// a single interchange, while walking before the first or after the last train is none.
+func (h *handlerImpl) getTravelCost(adjMatrix adjacencyMatrix, from vertexState, j int, line string, ht types.HourType, backward bool, mode types.RouteMode) int {
+	i := from.stationIdx
+	if mode == types.RMStops {
+		return adjMatrix[i][j].lines[line].defaults
+	}
+
+	fromLine, toLine := from.line, line
+	if backward {
+		fromLine, toLine = line, from.line
+	}
+
+	cost := h.getEdgeWeight(adjMatrix, i, j, line, ht)
+	if from.line == "" && !backward {
+		cost = cost + getWaitTime(line, ht) // first boarding.
+	}
+	if isInterchange(fromLine, toLine) {
+		cost = cost + getWaitTime(toLine, ht)
+		if fromLine != walkLine {
+			cost = cost + getInterchangeCost(i, fromLine, toLine, ht) // walking time already includes the change.
+		}
+	}
+	if mode == types.RMTransfers && isInterchange(from.trainLine, line) {
+		cost = cost + interchangePenalty
+	}
+	return cost
+}
+
+// isInterchange checks whether changing between given train lines boards another train.
+// Starting to walk is not an interchange, but boarding a train after walking is. To count interchanges,
+// pass the last train line taken before walking, so that a walk before the first train is not counted.
+func isInterchange(fromLine, toLine string) bool {
+	return fromLine != "" && toLine != "" && fromLine != toLine && toLine != walkLine
+}
+
+// getHourTypeAt returns hour type at given minutes after start time. Zero start time has invalid hour type.
+// When going back in time, it returns hour type of the minute before, when the rider is still travelling.
+func getHourTypeAt(startTime time.Time, minutes int, backward bool) types.HourType {
+	if startTime.IsZero() {
+		return types.HTInvalid
+	}
+	if backward {
+		minutes = minutes + 1
+	}
+	return types.GetHourType(addMinutes(startTime, minutes, backward))
+}
+
+// addMinutes returns clock time given minutes after (or before, when going back in time) given time.
+func addMinutes(t time.Time, minutes int, backward bool) time.Time {
+	if backward {
+		minutes = -minutes
+	}
+	return t.Add(time.Duration(minutes) * time.Minute)
+}
+
+// getEdgeWeight returns weight of an edge for given train line.
+func (h *handlerImpl) getEdgeWeight(adjMatrix adjacencyMatrix, i, j int, line string, ht types.HourType) int {
+	w := adjMatrix[i][j].lines[line]
+
 	var weight int
 	switch ht {
 	case types.HTNonPeak:
-		weight = adjMatrix[i][j].weight.nonPeakHour
+		weight = w.nonPeakHour
 	case types.HTPeak:
-		weight = adjMatrix[i][j].weight.peakHour
+		weight = w.peakHour
 	case types.HTNight:
-		weight = adjMatrix[i][j].weight.nightHour
+		weight = w.nightHour
 	default:
-		weight = adjMatrix[i][j].weight.defaults
+		weight = w.defaults
 	}
 	return weight
 }
@@ -36,6 +36,12 @@ func main() {
 
 	// API handlers.
 	router.GET("/routes", h.Routes)
+	router.GET("/reachable", h.Reachable)
+	router.POST("/matrix", h.Matrix)
+	router.GET("/stations/search", h.SearchStations)
+	router.POST("/admin/disruptions", h.AddDisruption)
+	router.GET("/admin/disruptions", h.ListDisruptions)
+	router.DELETE("/admin/disruptions/:id", h.RemoveDisruption)
 
 	// run app on the specified port
 	router.Run(":" + port)
@@ -21,7 +21,7 @@ func GetHourType(t time.Time) HourType {
 	weekday := t.Weekday()
 	hrs := t.Hour()
 
-	if hrs >= 22 && hrs < 6 {
+	if hrs >= 22 || hrs < 6 {
 		return HTNight
 	}
 
@@ -43,3 +43,16 @@ func ConvertToHourType(str string) HourType {
 	}
 	return HTInvalid
 }
+
+// String returns string representation of HourType
+func (ht HourType) String() string {
+	switch ht {
+	case HTPeak:
+		return "Peak"
+	case HTNonPeak:
+		return "NonPeak"
+	case HTNight:
+		return "Night"
+	}
+	return "Invalid"
+}
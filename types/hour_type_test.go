@@ -17,6 +17,14 @@ func TestGetHourType(t *testing.T) {
 		journeyTime, _ := time.Parse("2006-01-02T15:04", "2020-04-10T20:04")
 		assert.Equal(t, HTPeak, GetHourType(journeyTime))
 	})
+
+	t.Run("night-hour", func(t *testing.T) {
+		journeyTime, _ := time.Parse("2006-01-02T15:04", "2020-04-10T23:04")
+		assert.Equal(t, HTNight, GetHourType(journeyTime))
+
+		journeyTime, _ = time.Parse("2006-01-02T15:04", "2020-04-11T05:30")
+		assert.Equal(t, HTNight, GetHourType(journeyTime))
+	})
 }
 
 func TestConvertToHourType(t *testing.T) {
@@ -28,3 +36,8 @@ func TestConvertToHourType(t *testing.T) {
 		assert.Equal(t, HTInvalid, ConvertToHourType("somethinh"))
 	})
 }
+
+func TestHourTypeString(t *testing.T) {
+	assert.Equal(t, "Night", HTNight.String())
+	assert.Equal(t, HTPeak, ConvertToHourType(HTPeak.String()))
+}
@@ -0,0 +1,47 @@
+package types
+
+// RouteMode ...
+type RouteMode int
+
+const (
+	// RMInvalid ...
+	RMInvalid RouteMode = iota
+	// RMStops ranks routes by number of stops.
+	RMStops
+	// RMTime ranks routes by travel time.
+	RMTime
+	// RMTransfers ranks routes by number of interchanges.
+	RMTransfers
+	// RMPareto returns every route which is not worse than another route on all of travel time, stops and interchanges.
+	RMPareto
+)
+
+// ConvertToRouteMode converts string to RouteMode
+func ConvertToRouteMode(str string) RouteMode {
+	switch str {
+	case "stops":
+		return RMStops
+	case "time":
+		return RMTime
+	case "transfers":
+		return RMTransfers
+	case "pareto":
+		return RMPareto
+	}
+	return RMInvalid
+}
+
+// String returns string representation of RouteMode
+func (m RouteMode) String() string {
+	switch m {
+	case RMStops:
+		return "stops"
+	case RMTime:
+		return "time"
+	case RMTransfers:
+		return "transfers"
+	case RMPareto:
+		return "pareto"
+	}
+	return "invalid"
+}
@@ -0,0 +1,19 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToRouteMode(t *testing.T) {
+	t.Run("happy-path", func(t *testing.T) {
+		assert.Equal(t, RMTransfers, ConvertToRouteMode("transfers"))
+		assert.Equal(t, "transfers", RMTransfers.String())
+		assert.Equal(t, RMPareto, ConvertToRouteMode(RMPareto.String()))
+	})
+
+	t.Run("invalid-mode", func(t *testing.T) {
+		assert.Equal(t, RMInvalid, ConvertToRouteMode("fastest"))
+	})
+}